@@ -0,0 +1,126 @@
+package goWake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// WakeAll sends a magic packet to every MAC address in macs using the same options.
+// Up to `WithConcurrency` magic packets are sent in parallel. A failure to wake one
+// host does not stop the others from being woken; the returned error joins the
+// errors of all failed MAC addresses, each prefixed with the address.
+// MAC addresses listed more than once, even in different formats, are only woken
+// once unless `WithAllowDuplicates` is set.
+func WakeAll(macs []string, opts ...Option) error {
+	return WakeAllContext(context.Background(), macs, opts...)
+}
+
+// WakeAllContext is like WakeAll but honors the cancellation and deadline of ctx.
+// Once ctx is done, no further magic packets are sent and the ones in flight are
+// aborted, e.g. pending Echo replies are no longer awaited. The returned error then
+// joins the errors of the MAC addresses handled so far with the context error.
+func WakeAllContext(ctx context.Context, macs []string, opts ...Option) error {
+	_, err := WakeAllResult(ctx, macs, opts...)
+	return err
+}
+
+// WakeAllResult is like WakeAllContext but additionally returns a `Result` holding
+// the attempts for all MAC addresses in the order of macs, and the number of
+// duplicate MAC addresses which have been skipped. The result is nil only if
+// the options are invalid.
+func WakeAllResult(ctx context.Context, macs []string, opts ...Option) (*Result, error) {
+	w, err := NewWaker(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return w.wakeAll(ctx, macs)
+}
+
+// wakeAll sends a magic packet to every MAC address in macs.
+func (w *Waker) wakeAll(ctx context.Context, macs []string) (*Result, error) {
+	var duplicates int
+	if !w.opt.allowDuplicates {
+		macs, duplicates = dedupMACs(macs)
+		if duplicates > 0 {
+			w.opt.logger.Debug("skipped duplicate mac addresses", logKeyCount, duplicates)
+		}
+	}
+
+	results := make([]*Result, len(macs))
+	err := forEach(ctx, len(macs), w.opt.concurrency, func(i int) error {
+		var err error
+		if results[i], err = w.wakeResult(ctx, macs[i]); err != nil {
+			return fmt.Errorf("%s: %w", macs[i], err)
+		}
+		return nil
+	})
+
+	result := &Result{DryRun: w.opt.dryRun, Duplicates: duplicates}
+	for _, r := range results {
+		if r != nil {
+			result.Attempts = append(result.Attempts, r.Attempts...)
+		}
+	}
+	return result, err
+}
+
+// dedupMACs removes the MAC addresses from macs which have the same canonical form,
+// see `CanonicalMAC`, and password as an earlier one, e.g. "00:11:22:33:44:55" and
+// "001122334455", and returns the number of removed addresses. Invalid addresses
+// are kept, so they are reported when woken.
+func dedupMACs(macs []string) ([]string, int) {
+	seen := make(map[string]bool, len(macs))
+	unique := make([]string, 0, len(macs))
+	for _, mac := range macs {
+		hwAddr, pw, err := ParseMACWithPassword(mac)
+		if err == nil {
+			key := hwAddr.String() + " " + string(pw)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		unique = append(unique, mac)
+	}
+	return unique, len(macs) - len(unique)
+}
+
+// forEach calls fn for every index below n, running up to concurrency calls
+// in parallel, and joins the returned errors in the order of their indices.
+// Once ctx is done, fn is not called for the remaining indices and the context
+// error is added to the returned errors.
+func forEach(ctx context.Context, n, concurrency int, fn func(i int) error) error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	var skipped int
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			skipped = n - i
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+
+	if skipped > 0 {
+		errs = append(errs, fmt.Errorf("skipped %d of %d: %w", skipped, n, ctx.Err()))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,67 @@
+package goWake
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWakeAllDuplicates(t *testing.T) {
+	macs := []string{"00:11:22:33:44:55", "0011.2233.4455", "66:77:88:99:aa:bb", "00-11-22-33-44-55", "001122334455"}
+
+	var d fakeDialer
+	result, err := WakeAllResult(context.Background(), macs, WithDialer(&d), WithBroadcast(testBroadcast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duplicates != 3 {
+		t.Errorf("skipped %d duplicates, want 3", result.Duplicates)
+	}
+	if got := len(d.dialed()); got != 2 {
+		t.Errorf("sent %d magic packets, want 2", got)
+	}
+
+	// Duplicates are sent with WithAllowDuplicates
+	d = fakeDialer{}
+	result, err = WakeAllResult(context.Background(), macs, WithDialer(&d), WithBroadcast(testBroadcast), WithAllowDuplicates())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duplicates != 0 {
+		t.Errorf("skipped %d duplicates, want 0", result.Duplicates)
+	}
+	if got := len(d.dialed()); got != len(macs) {
+		t.Errorf("sent %d magic packets, want %d", got, len(macs))
+	}
+}
+
+func TestWakeHostsRateLimit(t *testing.T) {
+	hosts := []Host{{MAC: "00:11:22:33:44:55"}, {MAC: "00:11:22:33:44:56"}, {MAC: "00:11:22:33:44:57"}, {MAC: "00:11:22:33:44:58"}}
+	const interval = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	var sent []time.Time
+	progress := WithProgress(func(ev ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, time.Now())
+	})
+
+	var d fakeDialer
+	err := WakeHosts(hosts, WithDialer(&d), WithBroadcast(testBroadcast), WithConcurrency(len(hosts)),
+		WithRateLimit(float64(time.Second/interval)), progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent) != len(hosts) {
+		t.Fatalf("sent %d magic packets, want %d", len(sent), len(hosts))
+	}
+	// Allow for the time between the limiter and the progress callback
+	for i := 1; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < interval*4/5 {
+			t.Errorf("magic packet %d sent %s after the previous one, want %s", i+1, gap, interval)
+		}
+	}
+}
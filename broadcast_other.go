@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package goWake
+
+// setBroadcast is a no-op on platforms without SO_BROADCAST.
+func setBroadcast(fd uintptr) error {
+	return nil
+}
+
+// broadcastError returns err unchanged.
+func broadcastError(err error) error {
+	return err
+}
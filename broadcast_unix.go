@@ -0,0 +1,24 @@
+//go:build unix
+
+package goWake
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// setBroadcast sets SO_BROADCAST on the socket fd, without which some kernels
+// reject sending to 255.255.255.255 with EACCES.
+func setBroadcast(fd uintptr) error {
+	return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+}
+
+// broadcastError adds a note to errors with which the kernel rejects a broadcast.
+func broadcastError(err error) error {
+	if errors.Is(err, syscall.EACCES) {
+		return fmt.Errorf("the operating system rejected the broadcast, check the firewall "+
+			"and the interface the socket is bound to: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package goWake
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestControlBroadcast(t *testing.T) {
+	lc := net.ListenConfig{Control: controlBroadcast}
+	conn, err := lc.ListenPacket(context.Background(), "udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.UDPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value int
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		value, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	if value == 0 {
+		t.Error("SO_BROADCAST is not set")
+	}
+}
+
+// badRawConn is a `syscall.RawConn` of a socket which has already been closed.
+type badRawConn struct{ syscall.RawConn }
+
+func (badRawConn) Control(f func(fd uintptr)) error {
+	f(^uintptr(0))
+	return nil
+}
+
+func TestControlBroadcastError(t *testing.T) {
+	err := controlBroadcast("udp4", "192.0.2.255:9", badRawConn{})
+	if err == nil || !strings.Contains(err.Error(), "unable to set SO_BROADCAST") {
+		t.Fatalf("error = %v, want a wrapped error setting SO_BROADCAST", err)
+	}
+}
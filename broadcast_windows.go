@@ -0,0 +1,23 @@
+package goWake
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// setBroadcast sets SO_BROADCAST on the socket fd. On Windows, broadcasts from a
+// socket bound to the address of an interface are otherwise not reliably sent
+// over that interface.
+func setBroadcast(fd uintptr) error {
+	return syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+}
+
+// broadcastError adds a note to errors with which Windows rejects a broadcast.
+func broadcastError(err error) error {
+	if errors.Is(err, syscall.WSAEACCES) {
+		return fmt.Errorf("the operating system rejected the broadcast, check the firewall "+
+			"and the interface the socket is bound to: %w", err)
+	}
+	return err
+}
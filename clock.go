@@ -0,0 +1,19 @@
+package goWake
+
+import "time"
+
+// clock is the source of time for the delays, schedules and durations of this
+// package, which tests replace with a fake to run them instantly. Deadlines of
+// sockets are enforced by the operating system and therefore always use the
+// real time. Delays wait on After rather than sleeping, so that they can be
+// interrupted by a context.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the default clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
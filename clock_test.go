@@ -0,0 +1,333 @@
+package goWake
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+// fakeClock is a clock whose time only advances when a test calls step, so delays
+// take no real time and the order of events is deterministic. The delays waited
+// for are kept for inspection.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+	waits  []time.Duration
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	timer := fakeTimer{c.now.Add(d), make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	c.fire()
+	return timer.c
+}
+
+// step waits until n timers are pending and then advances the time to the
+// earliest of them, firing every timer which is due.
+func (c *fakeClock) step(t *testing.T, n int) {
+	t.Helper()
+	c.wait(t, n)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	next := c.timers[0].at
+	for _, timer := range c.timers {
+		if timer.at.Before(next) {
+			next = timer.at
+		}
+	}
+	if next.After(c.now) {
+		c.now = next
+	}
+	c.fire()
+}
+
+// advance moves the time forward by d, firing every timer which is due.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// wait waits until n timers are pending.
+func (c *fakeClock) wait(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		c.mu.Lock()
+		pending := len(c.timers)
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d timers never became pending", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// fire fires and removes the timers which are due. c.mu must be held.
+func (c *fakeClock) fire() {
+	c.timers = slices.DeleteFunc(c.timers, func(timer fakeTimer) bool {
+		if timer.at.After(c.now) {
+			return false
+		}
+		timer.c <- c.now
+		return true
+	})
+}
+
+// delays returns the durations waited for.
+func (c *fakeClock) delays() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.waits)
+}
+
+// withClock replaces the real clock with c.
+func withClock(c clock) Option {
+	return func(p *options) {
+		p.clock = c
+	}
+}
+
+// async runs f in a goroutine and returns a channel receiving its error.
+func async(f func() error) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+	return done
+}
+
+func TestRepeatDelayClock(t *testing.T) {
+	clk := newFakeClock()
+	var d fakeDialer
+	done := async(func() error {
+		return Wake("00:11:22:33:44:55", withClock(clk), WithDialer(&d), WithBroadcast(testBroadcast),
+			WithRepeat(3), WithRepeatDelay(time.Minute))
+	})
+	clk.step(t, 1)
+	clk.step(t, 1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := clk.delays(), []time.Duration{time.Minute, time.Minute}; !slices.Equal(got, want) {
+		t.Errorf("delays = %v, want %v", got, want)
+	}
+	if got := len(d.dialed()[0].packets()); got != 3 {
+		t.Errorf("wrote %d packets, want 3", got)
+	}
+}
+
+func TestBackoffClock(t *testing.T) {
+	clk := newFakeClock()
+	d := fakeDialer{newConn: func() *fakeConn { return &fakeConn{writeErr: os.ErrDeadlineExceeded} }}
+	done := async(func() error {
+		return Wake("00:11:22:33:44:55", withClock(clk), WithDialer(&d), WithBroadcast(testBroadcast),
+			WithRetries(3), WithBackoff(time.Minute))
+	})
+	for range 3 {
+		clk.step(t, 1)
+	}
+	if err := <-done; !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("error = %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}
+	if got := clk.delays(); !slices.Equal(got, want) {
+		t.Errorf("delays = %v, want %v", got, want)
+	}
+	if got := len(d.dialed()); got != 4 {
+		t.Errorf("dialed %d connections, want 4", got)
+	}
+}
+
+func TestWakeContextAtClock(t *testing.T) {
+	clk := newFakeClock()
+	var d fakeDialer
+	at := clk.Now().Add(time.Hour)
+	done := async(func() error {
+		return WakeContextAt(context.Background(), at, "00:11:22:33:44:55", withClock(clk), WithDialer(&d), WithBroadcast(testBroadcast))
+	})
+	clk.step(t, 1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := len(d.dialed()); got != 1 {
+		t.Errorf("dialed %d connections, want 1", got)
+	}
+	if now := clk.Now(); !now.Equal(at) {
+		t.Errorf("sent at %s, want %s", now, at)
+	}
+
+	// Nothing is sent once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d = fakeDialer{}
+	err := WakeContextAt(ctx, clk.Now().Add(time.Hour), "00:11:22:33:44:55", withClock(clk), WithDialer(&d), WithBroadcast(testBroadcast))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+	if got := len(d.dialed()); got != 0 {
+		t.Errorf("dialed %d connections after cancellation", got)
+	}
+}
+
+func TestWakeEveryClock(t *testing.T) {
+	clk := newFakeClock()
+	start := clk.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var d fakeDialer
+	done := async(func() error {
+		return WakeEvery(ctx, time.Hour, "00:11:22:33:44:55", withClock(clk), WithDialer(&d), WithBroadcast(testBroadcast))
+	})
+	clk.step(t, 1)
+	clk.step(t, 1)
+	clk.wait(t, 1) // the interval after the third wake
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want %v", err, context.Canceled)
+	}
+
+	if got := len(d.dialed()); got != 3 {
+		t.Errorf("woke %d times, want 3", got)
+	}
+	if got := clk.Now().Sub(start); got != 2*time.Hour {
+		t.Errorf("woke for %s, want %s", got, 2*time.Hour)
+	}
+}
+
+func TestWakeAndWaitClock(t *testing.T) {
+	clk := newFakeClock()
+	start := clk.Now()
+	errDown := errors.New("host is down")
+	check := func(ctx context.Context) error { return errDown }
+
+	// The poll interval and the timeout are both pending while waiting
+	done := async(func() error {
+		_, err := WakeAndWait("00:11:22:33:44:55", check, withClock(clk), WithDialer(&fakeDialer{}), WithBroadcast(testBroadcast),
+			WithWaitTimeout(time.Minute), WithPollInterval(10*time.Second))
+		return err
+	})
+	for range 6 {
+		clk.step(t, 2)
+	}
+	if err := <-done; !errors.Is(err, errDown) {
+		t.Fatalf("error = %v, want %v", err, errDown)
+	}
+	if elapsed := clk.Now().Sub(start); elapsed != time.Minute {
+		t.Errorf("gave up after %s, want %s", elapsed, time.Minute)
+	}
+
+	// The elapsed time is measured by the clock
+	var checks int
+	check = func(ctx context.Context) error {
+		if checks++; checks < 3 {
+			return errDown
+		}
+		return nil
+	}
+	var elapsed time.Duration
+	done = async(func() (err error) {
+		elapsed, err = WakeAndWait("00:11:22:33:44:55", check, withClock(clk), WithDialer(&fakeDialer{}), WithBroadcast(testBroadcast),
+			WithWaitTimeout(time.Minute), WithPollInterval(10*time.Second))
+		return err
+	})
+	clk.step(t, 2)
+	clk.step(t, 2)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed != 20*time.Second {
+		t.Errorf("elapsed = %s, want %s", elapsed, 20*time.Second)
+	}
+}
+
+func TestWakeAndWaitCadence(t *testing.T) {
+	clk := newFakeClock()
+	errDown := errors.New("host is down")
+
+	// Every check takes 4s of the 10s interval
+	var checkedAt []time.Time
+	check := func(ctx context.Context) error {
+		checkedAt = append(checkedAt, clk.Now())
+		clk.advance(4 * time.Second)
+		return errDown
+	}
+	done := async(func() error {
+		_, err := WakeAndWait("00:11:22:33:44:55", check, withClock(clk), WithDialer(&fakeDialer{}), WithBroadcast(testBroadcast),
+			WithWaitTimeout(time.Minute), WithPollInterval(10*time.Second))
+		return err
+	})
+	for range 6 {
+		clk.step(t, 2)
+	}
+	if err := <-done; !errors.Is(err, errDown) {
+		t.Fatalf("error = %v, want %v", err, errDown)
+	}
+
+	if len(checkedAt) < 6 {
+		t.Fatalf("checked %d times within a minute, want 6", len(checkedAt))
+	}
+	for i := 1; i < len(checkedAt); i++ {
+		if d := checkedAt[i].Sub(checkedAt[i-1]); d != 10*time.Second {
+			t.Errorf("check %d started %s after the previous one, want 10s", i+1, d)
+		}
+	}
+	for _, d := range clk.delays()[1:] {
+		if d != 6*time.Second {
+			t.Errorf("waited %s between checks, want 6s", d)
+		}
+	}
+}
+
+func TestEchoRTTClock(t *testing.T) {
+	clk := newFakeClock()
+	d := fakeDialer{newPacketConn: func() *fakePacketConn {
+		conn := newFakePacketConn()
+		conn.respond = func(b []byte, addr net.Addr) []fakePacket {
+			clk.advance(25 * time.Millisecond)
+			return echoResponder(b, addr)
+		}
+		return conn
+	}}
+
+	result, err := WakeResult("00:11:22:33:44:55", withClock(clk), WithDialer(&d), WithProtocol(protocol.Echo),
+		WithTargetIP(net.IPv4(192, 0, 2, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rtt := result.Attempts[0].RTT; rtt != 25*time.Millisecond {
+		t.Errorf("RTT = %s, want 25ms", rtt)
+	}
+}
@@ -0,0 +1,157 @@
+// Command gowake sends Wake-on-LAN magic packets.
+//
+// Usage:
+//
+//	gowake [flags] [mac ...]
+//
+// The MAC addresses are taken from the --mac flag and the arguments. If neither
+// is given, or an argument is "-", they are read from standard input, one per
+// line; empty lines and lines starting with # are ignored.
+//
+// Flags:
+//
+//	--mac string          MAC address to wake
+//	--iface string        network interface to send the magic packet over
+//	--protocol string     protocol to use: discard, echo, ethernet or auto (default "discard")
+//	--port int            UDP destination port (default 9)
+//	--repeat int          number of times the magic packet is sent (default 1)
+//	--password string     SecureOn password, e.g. aa:bb:cc:dd:ee:ff
+//	--broadcast string    broadcast address to send the magic packet to
+//	--list-interfaces     list the interfaces magic packets can be sent over and exit
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mitsimi/goWake/v2"
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gowake: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the command line args and wakes the given hosts.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("gowake", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gowake [flags] [mac ...]")
+		flags.PrintDefaults()
+	}
+
+	mac := flags.String("mac", "", "MAC address to wake")
+	iface := flags.String("iface", "", "network interface to send the magic packet over")
+	proto := flags.String("protocol", protocol.Discard.String(), "protocol to use: discard, echo, ethernet or auto")
+	port := flags.Int("port", 9, "UDP destination port")
+	repeat := flags.Int("repeat", 1, "number of times the magic packet is sent")
+	password := flags.String("password", "", "SecureOn password, e.g. aa:bb:cc:dd:ee:ff")
+	broadcast := flags.String("broadcast", "", "broadcast address to send the magic packet to")
+	listIfaces := flags.Bool("list-interfaces", false, "list the interfaces magic packets can be sent over and exit")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *listIfaces {
+		return listInterfaces(stdout)
+	}
+
+	p, err := protocol.Parse(*proto)
+	if err != nil {
+		return err
+	}
+
+	opts := []goWake.Option{
+		goWake.WithProtocol(p),
+		goWake.WithInterface(*iface),
+		goWake.WithPort(*port),
+		goWake.WithRepeat(*repeat),
+	}
+	if *password != "" {
+		pw, err := goWake.ParsePassword(*password)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, goWake.WithPassword(pw))
+	}
+	if *broadcast != "" {
+		ip := net.ParseIP(*broadcast)
+		if ip == nil {
+			return fmt.Errorf("invalid broadcast address %q", *broadcast)
+		}
+		opts = append(opts, goWake.WithBroadcast(ip))
+	}
+
+	macs, err := collectMACs(*mac, flags.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if len(macs) == 0 {
+		return errors.New("no MAC address given")
+	}
+
+	return goWake.WakeAll(macs, opts...)
+}
+
+// collectMACs returns the MAC addresses given by the --mac flag and the arguments,
+// reading them from stdin if there are none or an argument is "-".
+func collectMACs(mac string, args []string, stdin io.Reader) ([]string, error) {
+	var macs []string
+	if mac != "" {
+		macs = append(macs, mac)
+	}
+
+	readStdin := mac == "" && len(args) == 0
+	for _, arg := range args {
+		if arg == "-" {
+			readStdin = true
+			continue
+		}
+		macs = append(macs, arg)
+	}
+
+	if readStdin {
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			macs = append(macs, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("unable to read MAC addresses: %w", err)
+		}
+	}
+	return macs, nil
+}
+
+// listInterfaces prints the interfaces magic packets can be sent over as a table.
+func listInterfaces(w io.Writer) error {
+	ifaces, err := goWake.ListWakeInterfaces()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tNAME\tMAC\tADDRESS\tBROADCAST")
+	for _, iface := range ifaces {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", iface.Index, iface.Name, iface.HardwareAddr, iface.IP, iface.Broadcast)
+	}
+	return tw.Flush()
+}
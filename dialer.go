@@ -0,0 +1,59 @@
+package goWake
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// A Dialer opens the sockets used for sending magic packets.
+// The default Dialer uses the net package, it can be replaced with `WithDialer`,
+// e.g. to inspect the written packets with fake connections in tests.
+type Dialer interface {
+	// DialUDP opens a UDP connection from laddr to raddr, used by the Discard protocol.
+	// If laddr is nil, the local address is chosen by the operating system.
+	DialUDP(network string, laddr, raddr *net.UDPAddr) (net.Conn, error)
+
+	// DialIP opens a raw IP socket bound to laddr, used by the Echo protocol.
+	// The socket must not be connected to a remote address since replies to broadcast
+	// echo requests come from the unicast address of the answering host.
+	// If laddr is nil, the local address is chosen by the operating system.
+	DialIP(network string, laddr *net.IPAddr) (net.PacketConn, error)
+}
+
+// netDialer is the default Dialer backed by the net package.
+type netDialer struct{}
+
+func (netDialer) DialUDP(network string, laddr, raddr *net.UDPAddr) (net.Conn, error) {
+	d := net.Dialer{Control: controlBroadcast}
+	if laddr != nil {
+		d.LocalAddr = laddr
+	}
+
+	conn, err := d.Dial(network, raddr.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (netDialer) DialIP(network string, laddr *net.IPAddr) (net.PacketConn, error) {
+	conn, err := net.ListenIP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// controlBroadcast is a `net.Dialer` Control hook which sets SO_BROADCAST on the
+// socket before it is connected, rather than relying on the default socket options.
+func controlBroadcast(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) { sockErr = setBroadcast(fd) }); err != nil {
+		return err
+	}
+	if sockErr != nil {
+		return fmt.Errorf("unable to set SO_BROADCAST: %w", sockErr)
+	}
+	return nil
+}
@@ -0,0 +1,427 @@
+package goWake
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// testBroadcast is a broadcast address of the documentation network, which makes
+// the wake functions send to a single target without looking at the interfaces.
+var testBroadcast = net.IPv4(192, 0, 2, 255)
+
+// fakeConn is a `net.Conn` which records the packets written to it instead of
+// sending them. Its zero value accepts every write.
+type fakeConn struct {
+	mu       sync.Mutex
+	writes   [][]byte
+	deadline time.Time
+	closed   bool
+
+	writeErr error // returned by every write if set
+	chunk    int   // the most bytes accepted per write if positive
+	block    bool  // writes block until the write deadline has passed
+	discard  bool  // writes are accepted without recording them
+	closeErr error // returned by Close if set
+}
+
+func (c *fakeConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+	if c.block {
+		if deadline.IsZero() {
+			return 0, errors.New("fakeConn: write would block forever")
+		}
+		time.Sleep(time.Until(deadline))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.closed:
+		return 0, net.ErrClosed
+	case !c.deadline.IsZero() && !time.Now().Before(c.deadline):
+		return 0, os.ErrDeadlineExceeded
+	case c.writeErr != nil:
+		return 0, c.writeErr
+	}
+
+	n := len(b)
+	if c.chunk > 0 {
+		n = min(n, c.chunk)
+	}
+	if !c.discard {
+		c.writes = append(c.writes, bytes.Clone(b[:n]))
+	}
+	return n, nil
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.closed = true
+	return c.closeErr
+}
+
+func (c *fakeConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *fakeConn) SetDeadline(t time.Time) error     { return c.SetWriteDeadline(t) }
+func (c *fakeConn) SetReadDeadline(t time.Time) error { return nil }
+func (c *fakeConn) Read(b []byte) (int, error)        { return 0, io.EOF }
+func (c *fakeConn) LocalAddr() net.Addr               { return &net.UDPAddr{} }
+func (c *fakeConn) RemoteAddr() net.Addr              { return &net.UDPAddr{} }
+
+func (c *fakeConn) packets() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+func (c *fakeConn) writeDeadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline
+}
+
+func (c *fakeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// fakePacket is a packet written to or read from a fakePacketConn.
+type fakePacket struct {
+	data []byte
+	addr net.Addr
+}
+
+// fakePacketConn is a `net.PacketConn` which records the packets written to it and
+// answers them with the packets returned by respond. Reads block until an answer
+// is available, the read deadline has passed or the connection is closed.
+type fakePacketConn struct {
+	mu            sync.Mutex
+	writes        []fakePacket
+	readDeadline  time.Time
+	writeDeadline time.Time
+	closed        bool
+
+	respond  func(b []byte, addr net.Addr) []fakePacket // answers a written packet if set
+	closeErr error                                      // returned by Close if set
+
+	replies chan fakePacket
+	done    chan struct{}
+}
+
+func newFakePacketConn() *fakePacketConn {
+	return &fakePacketConn{replies: make(chan fakePacket, 16), done: make(chan struct{})}
+}
+
+func (c *fakePacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, net.ErrClosed
+	}
+	if !c.writeDeadline.IsZero() && !time.Now().Before(c.writeDeadline) {
+		c.mu.Unlock()
+		return 0, os.ErrDeadlineExceeded
+	}
+	c.writes = append(c.writes, fakePacket{bytes.Clone(b), addr})
+	respond := c.respond
+	c.mu.Unlock()
+
+	if respond != nil {
+		for _, p := range respond(b, addr) {
+			c.replies <- p
+		}
+	}
+	return len(b), nil
+}
+
+func (c *fakePacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.mu.Lock()
+	deadline := c.readDeadline
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p := <-c.replies:
+		return copy(b, p.data), p.addr, nil
+	case <-c.done:
+		return 0, nil, net.ErrClosed
+	case <-timeout:
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *fakePacketConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.closed = true
+	close(c.done)
+	return c.closeErr
+}
+
+func (c *fakePacketConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+func (c *fakePacketConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+func (c *fakePacketConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	return nil
+}
+
+func (c *fakePacketConn) LocalAddr() net.Addr { return &net.IPAddr{} }
+
+func (c *fakePacketConn) packets() []fakePacket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+func (c *fakePacketConn) deadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readDeadline
+}
+
+func (c *fakePacketConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// echoResponder answers ICMP and ICMPv6 Echo Requests like a host which is up.
+func echoResponder(b []byte, addr net.Addr) []fakePacket {
+	return []fakePacket{{echoReplyFor(b), addr}}
+}
+
+// echoReplyFor returns the Echo Reply to the given Echo Request, or nil if it is none.
+func echoReplyFor(request []byte) []byte {
+	proto, replyType := protocolICMP, icmp.Type(ipv4.ICMPTypeEchoReply)
+	m, err := icmp.ParseMessage(proto, request)
+	if err == nil && m.Type == ipv6.ICMPTypeEchoRequest {
+		proto, replyType = protocolICMPv6, ipv6.ICMPTypeEchoReply
+		m, err = icmp.ParseMessage(proto, request)
+	}
+	if err != nil || m.Type != ipv4.ICMPTypeEcho && m.Type != ipv6.ICMPTypeEchoRequest {
+		return nil
+	}
+
+	m.Type = replyType
+	reply, err := m.Marshal(nil)
+	if err != nil {
+		return nil
+	}
+	return reply
+}
+
+// fakeDialer is a `Dialer` returning fake connections, which it keeps for inspection.
+type fakeDialer struct {
+	mu          sync.Mutex
+	conns       []*fakeConn
+	raddrs      []*net.UDPAddr
+	packetConns []*fakePacketConn
+
+	newConn       func() *fakeConn                         // builds the connections of DialUDP if set
+	newPacketConn func() *fakePacketConn                   // builds the connections of DialIP, which answer echo requests by default
+	dialErr       func(network string, laddr net.IP) error // fails dialing from laddr if it returns an error
+}
+
+func (d *fakeDialer) DialUDP(network string, laddr, raddr *net.UDPAddr) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var localIP net.IP
+	if laddr != nil {
+		localIP = laddr.IP
+	}
+	if d.dialErr != nil {
+		if err := d.dialErr(network, localIP); err != nil {
+			return nil, err
+		}
+	}
+
+	conn := &fakeConn{}
+	if d.newConn != nil {
+		conn = d.newConn()
+	}
+	d.conns = append(d.conns, conn)
+	d.raddrs = append(d.raddrs, raddr)
+	return conn, nil
+}
+
+func (d *fakeDialer) DialIP(network string, laddr *net.IPAddr) (net.PacketConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var localIP net.IP
+	if laddr != nil {
+		localIP = laddr.IP
+	}
+	if d.dialErr != nil {
+		if err := d.dialErr(network, localIP); err != nil {
+			return nil, err
+		}
+	}
+
+	conn := newFakePacketConn()
+	conn.respond = echoResponder
+	if d.newPacketConn != nil {
+		conn = d.newPacketConn()
+	}
+	d.packetConns = append(d.packetConns, conn)
+	return conn, nil
+}
+
+// dialed returns the connections opened by DialUDP.
+func (d *fakeDialer) dialed() []*fakeConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conns
+}
+
+// dialedIP returns the connections opened by DialIP.
+func (d *fakeDialer) dialedIP() []*fakePacketConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.packetConns
+}
+
+func TestWithDialer(t *testing.T) {
+	const mac = "00:11:22:33:44:55"
+	want, err := PacketBytes(mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d fakeDialer
+	if err := Wake(mac, WithDialer(&d), WithBroadcast(testBroadcast), WithPort(7), WithRepeat(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	conns := d.dialed()
+	if len(conns) != 1 {
+		t.Fatalf("dialed %d connections, want 1", len(conns))
+	}
+	if got, want := d.raddrs[0].String(), "192.0.2.255:7"; got != want {
+		t.Errorf("dialed %s, want %s", got, want)
+	}
+	packets := conns[0].packets()
+	if len(packets) != 2 {
+		t.Fatalf("wrote %d packets, want 2", len(packets))
+	}
+	for i, p := range packets {
+		if !bytes.Equal(p, want) {
+			t.Errorf("packet %d = %x, want %x", i, p, want)
+		}
+	}
+	if !conns[0].isClosed() {
+		t.Error("connection not closed")
+	}
+}
+
+func TestWithDialerEcho(t *testing.T) {
+	const mac = "00:11:22:33:44:55"
+	want, err := PacketBytes(mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d fakeDialer
+	target := net.IPv4(192, 0, 2, 10)
+	result, err := WakeResult(mac, WithDialer(&d), WithProtocol(protocol.Echo), WithTargetIP(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conns := d.dialedIP()
+	if len(conns) != 1 {
+		t.Fatalf("dialed %d connections, want 1", len(conns))
+	}
+	packets := conns[0].packets()
+	if len(packets) != 1 {
+		t.Fatalf("wrote %d packets, want 1", len(packets))
+	}
+	m, err := icmp.ParseMessage(protocolICMP, packets[0].data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if echo, ok := m.Body.(*icmp.Echo); !ok || !bytes.Equal(echo.Data, want) {
+		t.Errorf("echo request %+v does not carry the magic packet", m.Body)
+	}
+	if got := packets[0].addr.String(); got != target.String() {
+		t.Errorf("sent to %s, want %s", got, target)
+	}
+	if peer := result.Attempts[0].Peer; !peer.Equal(target) {
+		t.Errorf("reply from %s, want %s", peer, target)
+	}
+}
+
+func TestCloseError(t *testing.T) {
+	const mac = "00:11:22:33:44:55"
+	errClose := errors.New("flush failed")
+	errWrite := errors.New("write failed")
+
+	// The close error is reported although the write succeeded
+	d := fakeDialer{newConn: func() *fakeConn { return &fakeConn{closeErr: errClose} }}
+	err := Wake(mac, WithDialer(&d), WithBroadcast(testBroadcast))
+	if !errors.Is(err, errClose) || !strings.Contains(err.Error(), "unable to close connection") {
+		t.Errorf("Wake error = %v, want %v", err, errClose)
+	}
+
+	// and joined with a write error
+	d = fakeDialer{newConn: func() *fakeConn { return &fakeConn{writeErr: errWrite, closeErr: errClose} }}
+	err = Wake(mac, WithDialer(&d), WithBroadcast(testBroadcast))
+	if !errors.Is(err, errWrite) || !errors.Is(err, errClose) {
+		t.Errorf("Wake error = %v, want both %v and %v", err, errWrite, errClose)
+	}
+
+	// The same holds for the connections of the Echo protocol
+	d = fakeDialer{newPacketConn: func() *fakePacketConn {
+		conn := newFakePacketConn()
+		conn.respond, conn.closeErr = echoResponder, errClose
+		return conn
+	}}
+	err = Wake(mac, WithDialer(&d), WithProtocol(protocol.Echo), WithTargetIP(net.IPv4(192, 0, 2, 10)))
+	if !errors.Is(err, errClose) {
+		t.Errorf("Wake error with the Echo protocol = %v, want %v", err, errClose)
+	}
+}
@@ -0,0 +1,79 @@
+package goWake
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+// Environment variables read by `WakeFromEnv`
+const (
+	EnvMAC      = "GOWAKE_MAC"
+	EnvIface    = "GOWAKE_IFACE"
+	EnvProtocol = "GOWAKE_PROTOCOL"
+	EnvPort     = "GOWAKE_PORT"
+	EnvRepeat   = "GOWAKE_REPEAT"
+)
+
+// WakeFromEnv sends a magic packet configured entirely by environment variables,
+// e.g. in containers or Kubernetes jobs:
+//
+//	GOWAKE_MAC       MAC address to wake (required)
+//	GOWAKE_IFACE     network interface, see `WithInterface`
+//	GOWAKE_PROTOCOL  protocol name, e.g. discard or echo (case-insensitive), see `WithProtocol`
+//	GOWAKE_PORT      UDP destination port, see `WithPort`
+//	GOWAKE_REPEAT    number of times the packet is sent, see `WithRepeat`
+//
+// The variables take precedence over the given options. Unset or empty variables
+// are ignored, except for GOWAKE_MAC which results in an error naming it.
+func WakeFromEnv(opts ...Option) error {
+	mac, envOpts, err := envOptions()
+	if err != nil {
+		return err
+	}
+	return Wake(mac, slices.Concat(opts, envOpts)...)
+}
+
+// envOptions returns the MAC address and the options set by the environment variables.
+func envOptions() (string, []Option, error) {
+	mac := os.Getenv(EnvMAC)
+	if mac == "" {
+		return "", nil, fmt.Errorf("environment variable %s is not set", EnvMAC)
+	}
+
+	var opts []Option
+	if iface := os.Getenv(EnvIface); iface != "" {
+		opts = append(opts, WithInterface(iface))
+	}
+
+	if name := os.Getenv(EnvProtocol); name != "" {
+		proto, err := protocol.Parse(name)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", EnvProtocol, err)
+		}
+		opts = append(opts, WithProtocol(proto))
+	}
+
+	for _, v := range []struct {
+		name   string
+		option func(int) Option
+	}{
+		{EnvPort, WithPort},
+		{EnvRepeat, WithRepeat},
+	} {
+		s := os.Getenv(v.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: invalid number %q", v.name, s)
+		}
+		opts = append(opts, v.option(n))
+	}
+
+	return mac, opts, nil
+}
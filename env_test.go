@@ -0,0 +1,76 @@
+package goWake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+func TestEnvOptions(t *testing.T) {
+	t.Setenv(EnvMAC, "00:11:22:33:44:55")
+	t.Setenv(EnvIface, "eth1")
+	t.Setenv(EnvProtocol, "ECHO")
+	t.Setenv(EnvPort, "7")
+	t.Setenv(EnvRepeat, "3")
+
+	mac, opts, err := envOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mac != "00:11:22:33:44:55" {
+		t.Errorf("mac = %q, want 00:11:22:33:44:55", mac)
+	}
+	opt := newOptions(opts)
+	if opt.iface != "eth1" || opt.protocol != protocol.Echo || opt.port != 7 || opt.repeat != 3 {
+		t.Errorf("options = interface %q, protocol %v, port %d, repeat %d, want eth1, echo, 7, 3",
+			opt.iface, opt.protocol, opt.port, opt.repeat)
+	}
+
+	// Empty variables are ignored
+	for _, name := range []string{EnvIface, EnvProtocol, EnvPort, EnvRepeat} {
+		t.Setenv(name, "")
+	}
+	if _, opts, err := envOptions(); err != nil || len(opts) != 0 {
+		t.Errorf("envOptions() = %d options, %v, want none", len(opts), err)
+	}
+}
+
+func TestEnvOptionsInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name, value string
+		want        string
+	}{
+		{EnvMAC, "", "environment variable GOWAKE_MAC is not set"},
+		{EnvProtocol, "bogus", "GOWAKE_PROTOCOL: "},
+		{EnvPort, "seven", `GOWAKE_PORT: invalid number "seven"`},
+		{EnvRepeat, "3x", `GOWAKE_REPEAT: invalid number "3x"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvMAC, "00:11:22:33:44:55")
+			t.Setenv(tt.name, tt.value)
+			if _, _, err := envOptions(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWakeFromEnv(t *testing.T) {
+	t.Setenv(EnvMAC, "00:11:22:33:44:55")
+	t.Setenv(EnvPort, "7")
+
+	// The variables take precedence over the options, which are not modified
+	var d fakeDialer
+	opts := make([]Option, 0, 8)
+	opts = append(opts, WithDialer(&d), WithBroadcast(testBroadcast), WithPort(9))
+	if err := WakeFromEnv(opts...); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.raddrs[0].String(); got != "192.0.2.255:7" {
+		t.Errorf("sent to %s, want 192.0.2.255:7", got)
+	}
+	if spare := opts[len(opts):cap(opts)]; spare[0] != nil {
+		t.Error("WakeFromEnv wrote to the spare capacity of the options")
+	}
+}
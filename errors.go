@@ -0,0 +1,51 @@
+package goWake
+
+import "errors"
+
+// Errors returned when sending a magic packet. They are wrapped with
+// further details, use `errors.Is` to check for them.
+var (
+	// ErrInvalidMAC is returned when a MAC address cannot be parsed.
+	ErrInvalidMAC = errors.New("invalid mac address")
+
+	// ErrMulticastMAC is returned when the MAC address to wake is a multicast or the
+	// broadcast address, which cannot belong to a network card, see `WithMulticastMAC`.
+	ErrMulticastMAC = errors.New("multicast mac address")
+
+	// ErrInvalidPassword is returned when a SecureOn password cannot be parsed
+	// or is not 4 or 6 bytes long.
+	ErrInvalidPassword = errors.New("invalid password")
+
+	// ErrInterfaceNotFound is returned when a network interface does not exist.
+	ErrInterfaceNotFound = errors.New("network interface not found")
+
+	// ErrNoSuitableAddress is returned when a network interface has no address
+	// the magic packet can be sent from.
+	ErrNoSuitableAddress = errors.New("no suitable address")
+
+	// ErrShortWrite is returned when fewer bytes than the whole magic packet were written.
+	ErrShortWrite = errors.New("short write")
+
+	// ErrUnsupportedProtocol is returned for protocols unknown to this package.
+	ErrUnsupportedProtocol = errors.New("unsupported protocol")
+
+	// ErrRawSocketPermission is returned when the process lacks the privileges
+	// to open the raw socket needed for sending ICMP messages or Ethernet frames.
+	ErrRawSocketPermission = errors.New("insufficient privileges for raw socket")
+
+	// ErrNeighborNotFound is returned by `MACFromIP` when the neighbor table of the
+	// operating system has no complete entry for the IP address.
+	ErrNeighborNotFound = errors.New("neighbor not found")
+
+	// ErrSuspiciousBroadcast is returned with `WithStrictBroadcast` if every broadcast
+	// address is likely wrong, e.g. the network address of the subnet. Without the
+	// option, such addresses are only logged as a warning.
+	ErrSuspiciousBroadcast = errors.New("suspicious broadcast address")
+)
+
+// Errors returned when parsing or validating a magic packet
+var (
+	ErrPacketLength = errors.New("invalid magic packet length")
+	ErrSyncHeader   = errors.New("invalid magic packet sync header")
+	ErrMACMismatch  = errors.New("inconsistent mac address repetitions in magic packet")
+)
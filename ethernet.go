@@ -0,0 +1,73 @@
+package goWake
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// EtherTypeWakeOnLAN is the EtherType of Ethernet frames carrying a magic packet.
+const EtherTypeWakeOnLAN = 0x0842
+
+// maxEthernetPayload is the largest payload of an Ethernet frame without jumbo frames.
+const maxEthernetPayload = 1500
+
+// broadcastMAC is the Ethernet broadcast address ff:ff:ff:ff:ff:ff.
+var broadcastMAC = net.HardwareAddr{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// sendRawEthernet sends the magic packet as payload of an Ethernet frame with
+// EtherType 0x0842 to the broadcast MAC address over the interface of the target.
+// It needs the privileges to open raw sockets.
+func sendRawEthernet(ctx context.Context, data []byte, t target, opt options) (int, error) {
+	if t.iface == "" {
+		return 0, fmt.Errorf("%w: the Ethernet protocol needs a network interface", ErrNoSuitableAddress)
+	}
+
+	iface, err := net.InterfaceByName(t.iface)
+	if err != nil {
+		return 0, interfaceNotFound(fmt.Sprintf("%q", t.iface), err)
+	}
+	if len(iface.HardwareAddr) != len(broadcastMAC) {
+		return 0, fmt.Errorf("%w: interface %s has no Ethernet address", ErrNoSuitableAddress, iface.Name)
+	}
+
+	if err := waitRateLimit(ctx, opt); err != nil {
+		return 0, err
+	}
+
+	frame := ethernetFrame(broadcastMAC, iface.HardwareAddr, data)
+	n, err := writeEthernetFrame(iface, frame)
+	if err != nil {
+		err = rawSocketError(err)
+	}
+	logWrite(opt, t, n, err)
+	return n, err
+}
+
+// EthernetFrame wraps payload, usually a serialized magic packet, in an Ethernet
+// header with EtherType 0x0842 for sending it with a layer 2 sender of its own.
+// The frame is addressed to dstMAC, or to the broadcast address ff:ff:ff:ff:ff:ff
+// if dstMAC is nil. The source address at bytes 6 to 11 is left zero for the
+// caller to fill in with the address of the sending interface.
+// It returns an error if payload is empty or exceeds the 1500 bytes of a frame.
+func EthernetFrame(dstMAC net.HardwareAddr, payload []byte) ([]byte, error) {
+	if dstMAC == nil {
+		dstMAC = broadcastMAC
+	}
+	if len(dstMAC) != len(broadcastMAC) {
+		return nil, fmt.Errorf("%w %q: expected %d bytes (got %d bytes)", ErrInvalidMAC, dstMAC, len(broadcastMAC), len(dstMAC))
+	}
+	if len(payload) == 0 || len(payload) > maxEthernetPayload {
+		return nil, fmt.Errorf("%w: payload of %d bytes does not fit an Ethernet frame", ErrPacketLength, len(payload))
+	}
+	return ethernetFrame(dstMAC, make(net.HardwareAddr, len(broadcastMAC)), payload), nil
+}
+
+// ethernetFrame wraps payload in an Ethernet header with EtherType 0x0842.
+func ethernetFrame(dst, src net.HardwareAddr, payload []byte) []byte {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, dst...)
+	frame = append(frame, src...)
+	frame = append(frame, EtherTypeWakeOnLAN>>8, EtherTypeWakeOnLAN&0xFF)
+	return append(frame, payload...)
+}
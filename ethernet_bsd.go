@@ -0,0 +1,65 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package goWake
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// writeEthernetFrame writes a complete Ethernet frame to the interface using a BPF device.
+func writeEthernetFrame(iface *net.Interface, frame []byte) (int, error) {
+	fd, err := openBPF()
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.Close(fd)
+
+	// Attach the device to the interface
+	var ifreq struct {
+		name [syscall.IFNAMSIZ]byte
+		_    [16]byte
+	}
+	copy(ifreq.name[:], iface.Name)
+	if err := ioctl(fd, syscall.BIOCSETIF, uintptr(unsafe.Pointer(&ifreq))); err != nil {
+		return 0, fmt.Errorf("unable to attach BPF device to interface %s: %w", iface.Name, err)
+	}
+
+	// Keep the source address of the frame instead of letting the kernel fill it in
+	enable := 1
+	if err := ioctl(fd, syscall.BIOCSHDRCMPLT, uintptr(unsafe.Pointer(&enable))); err != nil {
+		return 0, err
+	}
+
+	return syscall.Write(fd, frame)
+}
+
+// openBPF opens the first available BPF device.
+func openBPF() (int, error) {
+	fd, err := syscall.Open("/dev/bpf", syscall.O_WRONLY, 0)
+	if err == nil {
+		return fd, nil
+	}
+
+	for i := 0; i < 256; i++ {
+		fd, err = syscall.Open(fmt.Sprintf("/dev/bpf%d", i), syscall.O_WRONLY, 0)
+		if err != syscall.EBUSY {
+			break
+		}
+	}
+	if err != nil {
+		return -1, &os.PathError{Op: "open", Path: "/dev/bpf", Err: err}
+	}
+	return fd, nil
+}
+
+// ioctl performs an ioctl system call on fd.
+func ioctl(fd int, req uint, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(req), arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
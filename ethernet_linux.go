@@ -0,0 +1,37 @@
+package goWake
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+)
+
+// writeEthernetFrame writes a complete Ethernet frame to the interface using an AF_PACKET socket.
+func writeEthernetFrame(iface *net.Interface, frame []byte) (int, error) {
+	proto := htons(EtherTypeWakeOnLAN)
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(proto))
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.Close(fd)
+
+	addr := syscall.SockaddrLinklayer{
+		Protocol: proto,
+		Ifindex:  iface.Index,
+		Halen:    uint8(len(broadcastMAC)),
+	}
+	copy(addr.Addr[:], broadcastMAC)
+
+	if err := syscall.Sendto(fd, frame, 0, &addr); err != nil {
+		return 0, err
+	}
+	return len(frame), nil
+}
+
+// htons converts a 16 bit value from host to network byte order, which only
+// swaps its bytes on little-endian hosts.
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
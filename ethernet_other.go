@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package goWake
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// writeEthernetFrame is not supported on this platform.
+func writeEthernetFrame(*net.Interface, []byte) (int, error) {
+	return 0, fmt.Errorf("%w: the Ethernet protocol is not available on %s", ErrUnsupportedProtocol, runtime.GOOS)
+}
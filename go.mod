@@ -1,3 +1,10 @@
 module github.com/mitsimi/goWake/v2
 
 go 1.23.0
+
+require (
+	golang.org/x/net v0.43.0
+	golang.org/x/sys v0.35.0
+	golang.org/x/time v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
+)
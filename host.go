@@ -0,0 +1,66 @@
+package goWake
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// Host describes a machine which can be woken, e.g. an entry of an inventory.
+type Host struct {
+	Name string `json:"name,omitempty"` // optional, used to identify the host in errors
+	MAC  string `json:"mac"`            // MAC address of the network card to wake
+	IP   net.IP `json:"ip,omitempty"`   // optional unicast address, see `WithTargetIP`
+	Port int    `json:"port,omitempty"` // optional UDP destination port, see `WithPort`
+}
+
+// String returns the name of the host, or its MAC address if it has no name.
+func (h Host) String() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.MAC
+}
+
+// wakeOptions returns opts extended by the options derived from the host.
+func (h Host) wakeOptions(opts []Option) options {
+	opt := newOptions(opts)
+	if h.IP != nil {
+		opt.targetIP = h.IP
+	}
+	if h.Port != 0 {
+		opt.port = h.Port
+	}
+	return opt
+}
+
+// WakeHost sends a magic packet to the MAC address of h. If h has an IP address,
+// the magic packet is sent to it as unicast target like with `WithTargetIP`,
+// otherwise it is broadcast as usual. A port of h overrides `WithPort`.
+func WakeHost(h Host, opts ...Option) error {
+	opt := h.wakeOptions(opts)
+	hwAddr, err := parseMACArg(h.MAC, &opt)
+	if err != nil {
+		return err
+	}
+	_, err = wake(context.Background(), hwAddr, opt)
+	return err
+}
+
+// WakeHosts sends a magic packet to every host in hosts, see `WakeHost`.
+// Like `WakeAll`, up to `WithConcurrency` magic packets are sent in parallel and
+// the returned error joins the errors of all failed hosts, each prefixed with the
+// name or, if it has none, the MAC address of the host.
+func WakeHosts(hosts []Host, opts ...Option) error {
+	opt := newOptions(opts)
+	if err := opt.validate(); err != nil {
+		return err
+	}
+
+	return forEach(context.Background(), len(hosts), opt.concurrency, func(i int) error {
+		if err := WakeHost(hosts[i], opts...); err != nil {
+			return fmt.Errorf("%s: %w", hosts[i], err)
+		}
+		return nil
+	})
+}
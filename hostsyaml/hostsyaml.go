@@ -0,0 +1,93 @@
+// Package hostsyaml loads the hosts to wake from YAML files. It is kept apart from
+// goWake, whose `goWake.LoadHosts` reads JSON, so that only programs importing it
+// depend on a YAML parser.
+package hostsyaml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	goWake "github.com/mitsimi/goWake/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// fields are the keys of a host entry, see `goWake.Host`.
+var fields = map[string]bool{"name": true, "mac": true, "ip": true, "port": true}
+
+// LoadHosts parses a YAML sequence of hosts, e.g.
+//
+//   - name: nas
+//     mac: 00:11:22:33:44:55
+//     ip: 192.168.1.10
+//     port: 7
+//   - mac: 66:77:88:99:aa:bb
+//
+// Like `goWake.LoadHosts`, only the MAC address is required, and it is validated with
+// `goWake.ParseMAC`. Errors report the line and, for invalid hosts, the position of
+// the entry in the sequence.
+func LoadHosts(r io.Reader) ([]goWake.Host, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to parse hosts: %w", err)
+	}
+
+	seq := &doc
+	if seq.Kind == yaml.DocumentNode && len(seq.Content) == 1 {
+		seq = seq.Content[0]
+	}
+	if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: hosts must be a YAML sequence", seq.Line)
+	}
+
+	hosts := make([]goWake.Host, 0, len(seq.Content))
+	for i, node := range seq.Content {
+		h, err := decodeHost(node)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d (line %d): %w", i+1, node.Line, err)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// decodeHost decodes a single host entry, rejecting unknown keys.
+func decodeHost(node *yaml.Node) (goWake.Host, error) {
+	var h goWake.Host
+	if node.Kind != yaml.MappingNode {
+		return h, fmt.Errorf("host must be a YAML mapping")
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; !fields[key.Value] {
+			return h, fmt.Errorf("line %d: unknown field %q", key.Line, key.Value)
+		}
+	}
+
+	if err := node.Decode(&h); err != nil {
+		return h, err
+	}
+	if _, err := goWake.ParseMAC(h.MAC); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// WakeFromFile loads the hosts from the YAML file at path, see `LoadHosts`,
+// and wakes all of them, see `goWake.WakeHosts`.
+func WakeFromFile(path string, opts ...goWake.Option) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hosts, err := LoadHosts(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return goWake.WakeHosts(hosts, opts...)
+}
@@ -0,0 +1,59 @@
+package hostsyaml
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	goWake "github.com/mitsimi/goWake/v2"
+)
+
+func TestLoadHosts(t *testing.T) {
+	hosts, err := LoadHosts(strings.NewReader(`
+- name: nas
+  mac: 00:11:22:33:44:55
+  ip: 192.168.1.10
+  port: 7
+- mac: 0011.2233.4466
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []goWake.Host{
+		{Name: "nas", MAC: "00:11:22:33:44:55", IP: net.IPv4(192, 168, 1, 10), Port: 7},
+		{MAC: "0011.2233.4466"},
+	}
+	if len(hosts) != len(want) {
+		t.Fatalf("got %d hosts, want %d", len(hosts), len(want))
+	}
+	for i, h := range hosts {
+		if h.Name != want[i].Name || h.MAC != want[i].MAC || !h.IP.Equal(want[i].IP) || h.Port != want[i].Port {
+			t.Errorf("host %d = %+v, want %+v", i, h, want[i])
+		}
+	}
+}
+
+func TestLoadHostsInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		yaml string
+		want string
+	}{
+		{"mac: 00:11:22:33:44:55", "line 1: hosts must be a YAML sequence"},
+		{"- mac: 00:11:22:33:44:55\n- mac: 00:11:22:33:44", "entry 2 (line 2)"},
+		{"- mac: 00:11:22:33:44:55\n  user: root", "entry 1 (line 1): line 2: unknown field \"user\""},
+		{"- 00:11:22:33:44:55", "entry 1 (line 1): host must be a YAML mapping"},
+		{"- mac: [", "unable to parse hosts"},
+	} {
+		_, err := LoadHosts(strings.NewReader(tt.yaml))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadHosts(%q) error = %v, want %q", tt.yaml, err, tt.want)
+		}
+	}
+
+	_, err := LoadHosts(strings.NewReader("- mac: 00:11:22:33:44"))
+	if !errors.Is(err, goWake.ErrInvalidMAC) {
+		t.Errorf("error = %v, want %v", err, goWake.ErrInvalidMAC)
+	}
+}
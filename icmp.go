@@ -0,0 +1,252 @@
+package goWake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Protocol numbers of ICMP and ICMPv6 used to parse replies
+const (
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+// minReadBufferSize is the size of an IPv4 header followed by the header of
+// an ICMP Echo Reply, the least needed to match a reply to its request.
+const minReadBufferSize = 28
+
+// echoID is the identifier of all echo requests sent by this process.
+var echoID = os.Getpid() & 0xffff
+
+// echoSeq is the sequence number of the last sent echo request.
+var echoSeq atomic.Uint32
+
+// echoReply describes the Echo Reply matching an Echo Request.
+type echoReply struct {
+	seq  int           // sequence number of the request, set even without a reply
+	peer net.IP        // source address of the reply
+	rtt  time.Duration // time between sending the request and receiving the reply
+	ttl  int           // TTL or hop limit of the reply, 0 if unknown
+}
+
+// sendICMPEcho sends the magic packet as payload of an ICMP Echo Request and awaits
+// the matching Echo Reply. Waiting for the reply ends as soon as ctx is done.
+func sendICMPEcho(ctx context.Context, data []byte, t target, opt options) (int, echoReply, error) {
+	return ping(ctx, data, t, opt)
+}
+
+// ICMPCheck returns a HostCheck which succeeds once ip answers an ICMP Echo Request.
+// Like the Echo protocol, it needs the privileges to open raw sockets, i.e. root or
+// the CAP_NET_RAW capability on Linux, and fails with `ErrRawSocketPermission` otherwise.
+func ICMPCheck(ip net.IP) HostCheck {
+	opt := newOptions(nil)
+	return func(ctx context.Context) error {
+		_, _, err := ping(ctx, []byte("goWake"), target{dest: ip}, opt)
+		return err
+	}
+}
+
+// rawSocketError wraps permission errors of raw sockets with `ErrRawSocketPermission`.
+func rawSocketError(err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("%w: run as root or grant the CAP_NET_RAW capability, "+
+			"or use the Discard protocol instead: %w", ErrRawSocketPermission, err)
+	}
+	return err
+}
+
+// replyReader returns a function reading the next ICMP message from conn into buf,
+// which returns the message as a slice of buf ending at the last byte read, along
+// with the TTL or hop limit of the packet carrying it, or 0 if it is unknown. The
+// TTL is taken from the control messages where the platform supports them, or from
+// the IP header if conn delivers messages along with their IPv4 header; the header
+// is stripped either way. Control messages are only read from the raw sockets of
+// the net package, not from connections of a custom `Dialer`.
+func replyReader(conn net.PacketConn, proto int) func(buf []byte) ([]byte, int, net.Addr, error) {
+	_, isIPConn := conn.(*net.IPConn)
+	if proto == protocolICMPv6 {
+		var pc *ipv6.PacketConn
+		if isIPConn {
+			if p := ipv6.NewPacketConn(conn); p.SetControlMessage(ipv6.FlagHopLimit, true) == nil {
+				pc = p
+			}
+		}
+		if pc != nil {
+			return func(buf []byte) ([]byte, int, net.Addr, error) {
+				n, cm, peer, err := pc.ReadFrom(buf)
+				if err != nil || cm == nil {
+					return buf[:n], 0, peer, err
+				}
+				return buf[:n], cm.HopLimit, peer, nil
+			}
+		}
+		return func(buf []byte) ([]byte, int, net.Addr, error) {
+			n, peer, err := conn.ReadFrom(buf)
+			return buf[:n], 0, peer, err
+		}
+	}
+
+	var pc *ipv4.PacketConn
+	if isIPConn {
+		if p := ipv4.NewPacketConn(conn); p.SetControlMessage(ipv4.FlagTTL, true) == nil {
+			pc = p
+		}
+	}
+	return func(buf []byte) ([]byte, int, net.Addr, error) {
+		var n, ttl int
+		var peer net.Addr
+		var err error
+		if pc != nil {
+			var cm *ipv4.ControlMessage
+			n, cm, peer, err = pc.ReadFrom(buf)
+			if cm != nil {
+				ttl = cm.TTL
+			}
+		} else {
+			n, peer, err = conn.ReadFrom(buf)
+		}
+		if err != nil {
+			return buf[:n], 0, peer, err
+		}
+
+		// No ICMP message type starts with the version nibble of an IPv4 header.
+		msg := buf[:n]
+		if len(msg) >= ipv4.HeaderLen && msg[0]>>4 == ipv4.Version {
+			if h, err := icmp.ParseIPv4Header(msg); err == nil && h.Len <= len(msg) {
+				return msg[h.Len:], h.TTL, peer, nil
+			}
+		}
+		return msg, ttl, peer, nil
+	}
+}
+
+// validChecksum reports whether the Internet checksum (RFC 1071) of the ICMP message b is
+// valid, i.e. whether the ones' complement sum of the message including its checksum
+// field is all ones.
+func validChecksum(b []byte) bool {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return sum == 0xffff
+}
+
+// ping sends an ICMP Echo Request carrying payload and awaits the matching Echo Reply,
+// identified by its echo identifier and sequence number, or any Echo Reply with
+// `WithEchoBestEffort`.
+// If the target has no local IP, the source address is chosen by the operating system.
+func ping(ctx context.Context, payload []byte, t target, opt options) (_ int, _ echoReply, err error) {
+	var localAddr *net.IPAddr
+	if t.localIP != nil {
+		localAddr = &net.IPAddr{IP: t.localIP, Zone: t.zone}
+	}
+
+	network, proto := "ip4:icmp", protocolICMP
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if t.dest.To4() == nil {
+		network, proto = "ip6:ipv6-icmp", protocolICMPv6
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	// The socket is not connected to the destination since replies to a
+	// broadcast request come from the unicast address of the host.
+	conn, err := opt.dialer.DialIP(network, localAddr)
+	if err != nil {
+		return 0, echoReply{}, rawSocketError(err)
+	}
+	defer closeConn(ctx, conn, &err)
+
+	// Closing the connection once ctx is done unblocks a pending read right away
+	// instead of at the read deadline. The callback runs at most once and never
+	// after stop, so it does not outlive the call.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	seq := int(echoSeq.Add(1) & 0xffff)
+	reply := echoReply{seq: seq}
+	msg := icmp.Message{
+		Type: requestType,
+		Body: &icmp.Echo{ID: echoID, Seq: seq, Data: payload},
+	}
+
+	// The checksum of ICMPv6 messages is computed by the kernel.
+	request, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, echoReply{}, err
+	}
+
+	// Send the packet over ICMP
+	if err := waitRateLimit(ctx, opt); err != nil {
+		return 0, reply, err
+	}
+
+	sentAt := opt.clock.Now()
+	written, err := conn.WriteTo(request, &net.IPAddr{IP: t.dest, Zone: t.zone})
+	logWrite(opt, t, written, err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return written, reply, ctx.Err()
+		}
+		return written, reply, err
+	}
+
+	// Wait for an echo response for the configured timeout, capped by the
+	// deadlines of the context and the options. The socket enforces the deadline
+	// in real time, while the round-trip time is measured by the clock.
+	ctxDeadline, _ := ctx.Deadline()
+	deadline := earliest(time.Now().Add(opt.echoTimeout), ctxDeadline, opt.deadline)
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return written, reply, err
+	}
+	read := replyReader(conn, proto)
+	buf := make([]byte, opt.readBufferSize)
+	for {
+		msg, ttl, peer, err := read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return written, reply, ctx.Err()
+			}
+			return written, reply, fmt.Errorf("no response received: %w", err)
+		}
+
+		// The kernel verifies the checksum of ICMPv6 messages, but not of ICMP ones.
+		// The checksum covers the whole message, so it cannot be verified if the
+		// message filled buf and may have been truncated; msg is a slice of buf
+		// ending at the last byte read, so that is the case if it reaches the end of buf.
+		truncated := len(msg) == cap(msg)
+		if opt.validateChecksum && proto == protocolICMP && !truncated && !validChecksum(msg) {
+			opt.logger.Debug("dropped ICMP message with invalid checksum", logKeyIP, peer, logKeyBytes, len(msg))
+			continue
+		}
+
+		// Raw sockets receive every ICMP message, so skip the ones which
+		// do not answer this request.
+		m, err := icmp.ParseMessage(proto, msg)
+		if err != nil || m.Type != replyType {
+			continue
+		}
+		if echo, ok := m.Body.(*icmp.Echo); ok && (opt.echoBestEffort || echo.ID == echoID && echo.Seq == seq) {
+			reply.rtt = opt.clock.Now().Sub(sentAt)
+			reply.ttl = ttl
+			if ipAddr, ok := peer.(*net.IPAddr); ok {
+				reply.peer = ipAddr.IP
+			}
+			return written, reply, nil
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package goWake
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// wakeLoopbackEcho sends an echo request carrying the magic packet to 127.0.0.1,
+// skipping the test without the privileges to open raw sockets.
+func wakeLoopbackEcho(t *testing.T, opts ...Option) *Result {
+	t.Helper()
+	opts = append([]Option{WithProtocol(protocol.Echo), WithTargetIP(net.IPv4(127, 0, 0, 1))}, opts...)
+	result, err := WakeResult("00:11:22:33:44:55", opts...)
+	if errors.Is(err, ErrRawSocketPermission) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestEchoLoopback(t *testing.T) {
+	result := wakeLoopbackEcho(t)
+
+	if len(result.Attempts) != 1 {
+		t.Fatalf("got %d attempts, want 1", len(result.Attempts))
+	}
+	a := result.Attempts[0]
+	if !a.Peer.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("reply from %s, want 127.0.0.1", a.Peer)
+	}
+	if a.RTT <= 0 {
+		t.Errorf("reply has RTT %s", a.RTT)
+	}
+}
+
+func TestEchoTimeout(t *testing.T) {
+	target := net.IPv4(192, 0, 2, 10)
+
+	var d fakeDialer
+	start := time.Now()
+	if err := Wake("00:11:22:33:44:55", WithDialer(&d), WithProtocol(protocol.Echo), WithTargetIP(target),
+		WithEchoTimeout(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := d.dialedIP()[0].deadline()
+	if deadline.Before(start.Add(time.Hour)) || deadline.After(time.Now().Add(time.Hour)) {
+		t.Errorf("read deadline %s is not an hour after %s", deadline, start)
+	}
+
+	// The deadline of the context caps the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := WakeContext(ctx, "00:11:22:33:44:55", WithDialer(&d), WithProtocol(protocol.Echo), WithTargetIP(target),
+		WithEchoTimeout(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	ctxDeadline, _ := ctx.Deadline()
+	if deadline := d.dialedIP()[1].deadline(); !deadline.Equal(ctxDeadline) {
+		t.Errorf("read deadline %s, want the context deadline %s", deadline, ctxDeadline)
+	}
+}
+
+func TestValidateChecksum(t *testing.T) {
+	target := net.IPv4(192, 0, 2, 10)
+	corrupt := func(b []byte, addr net.Addr) []fakePacket {
+		reply := echoReplyFor(b)
+		reply[2] ^= 0xff
+		return []fakePacket{{reply, addr}}
+	}
+	wake := func(respond func(b []byte, addr net.Addr) []fakePacket, opts ...Option) error {
+		d := fakeDialer{newPacketConn: func() *fakePacketConn {
+			conn := newFakePacketConn()
+			conn.respond = respond
+			return conn
+		}}
+		opts = append([]Option{WithDialer(&d), WithProtocol(protocol.Echo), WithTargetIP(target),
+			WithEchoTimeout(50 * time.Millisecond)}, opts...)
+		return Wake("00:11:22:33:44:55", opts...)
+	}
+
+	// Replies with a wrong checksum are accepted by default
+	if err := wake(corrupt); err != nil {
+		t.Errorf("corrupted reply without WithValidateChecksum: %v", err)
+	}
+
+	// With WithValidateChecksum, they are ignored
+	if err := wake(corrupt, WithValidateChecksum()); err == nil {
+		t.Error("corrupted reply accepted with WithValidateChecksum")
+	}
+	if err := wake(func(b []byte, addr net.Addr) []fakePacket {
+		return append(corrupt(b, addr), echoResponder(b, addr)...)
+	}, WithValidateChecksum()); err != nil {
+		t.Errorf("valid reply after a corrupted one: %v", err)
+	}
+
+	// Truncated replies cannot be verified and are accepted
+	if err := wake(corrupt, WithValidateChecksum(), WithReadBufferSize(minReadBufferSize)); err != nil {
+		t.Errorf("truncated reply with WithValidateChecksum: %v", err)
+	}
+}
+
+func TestValidChecksum(t *testing.T) {
+	reply := echoReplyFor(mustMarshal(t, []byte("magic")))
+	if !validChecksum(reply) {
+		t.Errorf("checksum of %x is invalid", reply)
+	}
+	reply[len(reply)-1] ^= 0x01
+	if validChecksum(reply) {
+		t.Errorf("checksum of corrupted %x is valid", reply)
+	}
+}
+
+// mustMarshal returns an ICMP Echo Request carrying payload.
+func mustMarshal(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	msg := icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: 1, Seq: 1, Data: payload}}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAutoProtocolFallback(t *testing.T) {
+	d := fakeDialer{dialErr: func(network string, laddr net.IP) error {
+		if strings.HasPrefix(network, "ip") {
+			return &net.OpError{Op: "listen", Net: network, Err: os.ErrPermission}
+		}
+		return nil
+	}}
+	result, err := WakeResult("00:11:22:33:44:55", WithDialer(&d), WithProtocol(protocol.Auto), WithBroadcast(testBroadcast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Attempts) != 1 || result.Attempts[0].Protocol != protocol.Discard {
+		t.Fatalf("attempts = %+v, want one with the Discard protocol", result.Attempts)
+	}
+	if got := len(d.dialed()); got != 1 {
+		t.Errorf("sent %d UDP packets, want 1", got)
+	}
+
+	// Echo is used where raw sockets are permitted
+	d = fakeDialer{}
+	result, err = WakeResult("00:11:22:33:44:55", WithDialer(&d), WithProtocol(protocol.Auto), WithTargetIP(net.IPv4(192, 0, 2, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Attempts[0].Protocol != protocol.Echo || len(d.dialed()) != 0 {
+		t.Errorf("attempts = %+v, want one with the Echo protocol", result.Attempts)
+	}
+}
+
+func TestEchoCancel(t *testing.T) {
+	// The fake host never replies, so the read blocks until the connection is closed
+	d := fakeDialer{newPacketConn: newFakePacketConn}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := WakeContext(ctx, "00:11:22:33:44:55", WithDialer(&d), WithProtocol(protocol.Echo),
+		WithTargetIP(net.IPv4(192, 0, 2, 10)), WithEchoTimeout(time.Hour))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("canceled read returned after %s", elapsed)
+	}
+	conns := d.dialedIP()
+	if len(conns) != 1 || len(conns[0].packets()) != 1 {
+		t.Fatal("echo request not sent before canceling")
+	}
+	if !conns[0].isClosed() {
+		t.Error("connection not closed")
+	}
+}
+
+func TestEchoTTL(t *testing.T) {
+	result := wakeLoopbackEcho(t)
+	if ttl := result.Attempts[0].TTL; ttl <= 0 || ttl > 255 {
+		t.Errorf("reply from localhost has TTL %d", ttl)
+	}
+
+	// Replies delivered with their IPv4 header report its TTL
+	target := net.IPv4(192, 0, 2, 10)
+	d := fakeDialer{newPacketConn: func() *fakePacketConn {
+		conn := newFakePacketConn()
+		conn.respond = func(b []byte, addr net.Addr) []fakePacket {
+			reply := echoReplyFor(b)
+			h := ipv4.Header{Version: ipv4.Version, Len: ipv4.HeaderLen, TotalLen: ipv4.HeaderLen + len(reply),
+				TTL: 57, Protocol: protocolICMP, Src: target, Dst: net.IPv4(192, 0, 2, 2)}
+			header, err := h.Marshal()
+			if err != nil {
+				t.Error(err)
+			}
+			return []fakePacket{{append(header, reply...), addr}}
+		}
+		return conn
+	}}
+	result, err := WakeResult("00:11:22:33:44:55", WithDialer(&d), WithProtocol(protocol.Echo), WithTargetIP(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := result.Attempts[0]; a.TTL != 57 || a.RTT <= 0 {
+		t.Errorf("reply has TTL %d and RTT %s, want TTL 57", a.TTL, a.RTT)
+	}
+}
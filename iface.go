@@ -0,0 +1,288 @@
+package goWake
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// InterfaceBroadcast returns the address a magic packet sent over the named
+// interface is delivered to: the broadcast address of its IPv4 subnet, or the
+// all-nodes multicast address ff02::1 if the interface only has IPv6 addresses.
+// It returns an error wrapping `ErrInterfaceNotFound` if the interface does not exist.
+func InterfaceBroadcast(name string) (net.IP, error) {
+	ipAddr, err := ipFromInterface(name, AddressFamilyIPv4)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := interfaceTarget(name, ipAddr)
+	if err != nil {
+		return nil, err
+	}
+	return t.dest, nil
+}
+
+// WakeInterface describes a network interface magic packets can be sent over.
+type WakeInterface struct {
+	Name         string
+	Index        int
+	HardwareAddr net.HardwareAddr
+	IP           *net.IPNet // the IPv4 address magic packets are sent from
+	Broadcast    net.IP     // the broadcast address of the subnet of IP
+	CanBroadcast bool       // whether the interface supports broadcasting (net.FlagBroadcast)
+	WoWLAN       WoWLAN     // only determined by WakeCapableInterfaces
+}
+
+// ListWakeInterfaces returns every network interface which is up, is not a loopback
+// interface and has an IPv4 address, together with the broadcast address magic
+// packets sent over it are delivered to. Interfaces without a suitable address are skipped.
+func ListWakeInterfaces() ([]WakeInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("unable to list network interfaces"), err)
+	}
+
+	var wakeIfaces []WakeInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		ipv4 := ipv4Addrs(addrs)
+		if len(ipv4) == 0 {
+			continue
+		}
+
+		broadcastAddr, err := SubnetBroadcast(ipv4[0])
+		if err != nil {
+			continue
+		}
+
+		wakeIfaces = append(wakeIfaces, WakeInterface{
+			Name:         iface.Name,
+			Index:        iface.Index,
+			HardwareAddr: iface.HardwareAddr,
+			IP:           ipv4[0],
+			Broadcast:    broadcastAddr,
+			CanBroadcast: iface.Flags&net.FlagBroadcast != 0,
+		})
+	}
+	return wakeIfaces, nil
+}
+
+// AddressFamily selects which addresses of an interface magic packets are sent from,
+// see `WithAddressFamily`. IPv4 packets are sent to the broadcast address of the
+// subnet, IPv6 packets to the all-nodes multicast address ff02::1.
+type AddressFamily int
+
+const (
+	AddressFamilyIPv4 AddressFamily = iota // IPv4, or IPv6 if the interface has no IPv4 address
+	AddressFamilyIPv6                      // IPv6, or IPv4 if the interface has no IPv6 address
+	AddressFamilyAuto                      // both IPv4 and IPv6 if the interface has addresses of both
+)
+
+// String returns "ipv4", "ipv6" or "auto".
+func (af AddressFamily) String() string {
+	switch af {
+	case AddressFamilyIPv4:
+		return "ipv4"
+	case AddressFamilyIPv6:
+		return "ipv6"
+	case AddressFamilyAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("AddressFamily(%d)", int(af))
+	}
+}
+
+// ipFromInterface returns a `*net.IPNet` from a network interface name,
+// preferring the given address family.
+func ipFromInterface(name string, af AddressFamily) (*net.IPNet, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, interfaceNotFound(fmt.Sprintf("%q", name), err)
+	}
+	return interfaceIP(iface, af)
+}
+
+// interfaceNotFound returns an error wrapping `ErrInterfaceNotFound` and err for the
+// interface described by desc, listing the names of the available interfaces.
+func interfaceNotFound(desc string, err error) error {
+	var names []string
+	if ifaces, ifaceErr := net.Interfaces(); ifaceErr == nil {
+		for _, iface := range ifaces {
+			names = append(names, iface.Name)
+		}
+	}
+	return fmt.Errorf("%w: %s (available: %s): %w", ErrInterfaceNotFound, desc, strings.Join(names, ", "), err)
+}
+
+// interfaceIP returns the first non-loopback address of a network interface, see interfaceIPs.
+func interfaceIP(iface *net.Interface, af AddressFamily) (*net.IPNet, error) {
+	ipNets, err := interfaceIPs(iface, af)
+	if err != nil {
+		return nil, err
+	}
+	return ipNets[0], nil
+}
+
+// interfaceIPs returns the first non-loopback IPv4 address of a network interface, or
+// its first non-loopback IPv6 address if it has no IPv4 address. With AddressFamilyIPv6,
+// IPv6 is preferred instead, and with AddressFamilyAuto, the first address of both
+// families is returned, IPv4 first. An interface without any such address, e.g. one
+// which is up but unconfigured, yields an error wrapping `ErrNoSuitableAddress`.
+func interfaceIPs(iface *net.Interface, af AddressFamily) ([]*net.IPNet, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list addresses of interface %s: %w", iface.Name, err)
+	}
+	return selectIPs(iface.Name, addrs, af)
+}
+
+// selectIPs returns the addresses among addrs of the named interface magic packets
+// are sent from, see interfaceIPs.
+func selectIPs(name string, addrs []net.Addr, af AddressFamily) ([]*net.IPNet, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%w: no address associated with interface %s", ErrNoSuitableAddress, name)
+	}
+
+	var ipv4, ipv6 *net.IPNet
+	if ipNets := ipv4Addrs(addrs); len(ipNets) > 0 {
+		ipv4 = ipNets[0]
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() == nil {
+			ipv6 = ipNet
+			break
+		}
+	}
+
+	var ipNets []*net.IPNet
+	switch {
+	case af == AddressFamilyAuto:
+		for _, ipNet := range []*net.IPNet{ipv4, ipv6} {
+			if ipNet != nil {
+				ipNets = append(ipNets, ipNet)
+			}
+		}
+	case af == AddressFamilyIPv6 && ipv6 != nil, ipv4 == nil && ipv6 != nil:
+		ipNets = []*net.IPNet{ipv6}
+	case ipv4 != nil:
+		ipNets = []*net.IPNet{ipv4}
+	}
+	if len(ipNets) == 0 {
+		return nil, fmt.Errorf("%w: no suitable IP address found for interface %s", ErrNoSuitableAddress, name)
+	}
+	return ipNets, nil
+}
+
+// loopbackIP returns the first loopback address of the named interface, preferring
+// IPv4 addresses, see `WithLoopbackAllowed`.
+func loopbackIP(name string) (*net.IPNet, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, interfaceNotFound(fmt.Sprintf("%q", name), err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list addresses of interface %s: %w", name, err)
+	}
+
+	var ipv6 *net.IPNet
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: ipNet.Mask}, nil
+		}
+		if ipv6 == nil {
+			ipv6 = ipNet
+		}
+	}
+	if ipv6 != nil {
+		return ipv6, nil
+	}
+	return nil, fmt.Errorf("%w: no suitable IP address found for interface %s", ErrNoSuitableAddress, name)
+}
+
+// ipv4Addrs returns the non-loopback IPv4 addresses among addrs in their 4 byte representation.
+func ipv4Addrs(addrs []net.Addr) []*net.IPNet {
+	var ipNets []*net.IPNet
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil {
+			ipNets = append(ipNets, &net.IPNet{IP: ip4, Mask: ipNet.Mask})
+		}
+	}
+	return ipNets
+}
+
+// interfaceByIP returns the local interface the given address is assigned to.
+func interfaceByIP(ip net.IP) (*net.Interface, *net.IPNet, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("unable to list network interfaces"), err)
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				if ip4 := ipNet.IP.To4(); ip4 != nil {
+					ipNet = &net.IPNet{IP: ip4, Mask: ipNet.Mask}
+				}
+				return &iface, ipNet, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: %s is not assigned to any local interface", ErrNoSuitableAddress, ip)
+}
+
+// SubnetBroadcast calculates the directed broadcast address of the given `*net.IPNet`,
+// e.g. 192.168.1.255 for 192.168.1.10/24. IPv4 addresses are accepted in both their
+// 4 and 16 byte representation and always result in a 4 byte broadcast address.
+func SubnetBroadcast(ipnet *net.IPNet) (net.IP, error) {
+	if ipnet == nil {
+		return nil, fmt.Errorf("no network given")
+	}
+
+	byteIp := []byte(ipnet.IP)
+	byteMask := []byte(ipnet.Mask)
+
+	// Normalize IPv4 addresses and masks to 4 bytes so that both have the same length
+	if ip4 := ipnet.IP.To4(); ip4 != nil {
+		byteIp = ip4
+		if len(byteMask) == net.IPv6len {
+			byteMask = byteMask[12:]
+		}
+	}
+
+	if len(byteIp) != len(byteMask) {
+		return nil, fmt.Errorf("mask %s does not match the address %s", ipnet.Mask, ipnet.IP)
+	}
+
+	broadcastIP := make([]byte, len(byteIp))
+	for i := range byteIp {
+		invertedMask := byteMask[i] ^ 0xff
+		broadcastIP[i] = byteIp[i]&byteMask[i] | invertedMask
+	}
+
+	return broadcastIP, nil
+}
@@ -0,0 +1,162 @@
+package goWake
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSubnetBroadcast(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		ipNet *net.IPNet
+		want  string
+	}{
+		{"192.168.1.10/24", &net.IPNet{IP: net.IP{192, 168, 1, 10}, Mask: net.CIDRMask(24, 32)}, "192.168.1.255"},
+		{"10.0.0.5/8", &net.IPNet{IP: net.IP{10, 0, 0, 5}, Mask: net.CIDRMask(8, 32)}, "10.255.255.255"},
+		{"16 byte IP", &net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}, "192.168.1.255"},
+		{"16 byte IP and mask", &net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(120, 128)}, "192.168.1.255"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SubnetBroadcast(tt.ipNet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want || len(got) != net.IPv4len {
+				t.Errorf("SubnetBroadcast = %s (%d bytes), want %s", got, len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSubnetBroadcastCIDR(t *testing.T) {
+	for cidr, want := range map[string]string{
+		"0.0.0.0/0":         "255.255.255.255",
+		"172.16.0.1/12":     "172.31.255.255",
+		"192.168.0.1/16":    "192.168.255.255",
+		"192.168.50.0/23":   "192.168.51.255",
+		"192.168.1.129/25":  "192.168.1.255",
+		"192.168.1.5/30":    "192.168.1.7",
+		"192.168.1.4/31":    "192.168.1.5",
+		"192.168.1.4/32":    "192.168.1.4",
+		"2001:db8::1/64":    "2001:db8::ffff:ffff:ffff:ffff",
+		"2001:db8::1/126":   "2001:db8::3",
+		"2001:db8:1::1/128": "2001:db8:1::1",
+	} {
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ipNet.IP = ip
+		got, err := SubnetBroadcast(ipNet)
+		if err != nil {
+			t.Errorf("SubnetBroadcast(%s): %v", cidr, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("SubnetBroadcast(%s) = %s, want %s", cidr, got, want)
+		}
+	}
+
+	mismatch := &net.IPNet{IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(24, 32)}
+	if _, err := SubnetBroadcast(mismatch); err == nil {
+		t.Errorf("SubnetBroadcast(%s) succeeded", mismatch)
+	}
+	if _, err := SubnetBroadcast(nil); err == nil {
+		t.Error("SubnetBroadcast(nil) succeeded")
+	}
+}
+
+func TestInterfaceNotFound(t *testing.T) {
+	err := Wake("00:11:22:33:44:55", WithInterface("doesnotexist0"))
+	if !errors.Is(err, ErrInterfaceNotFound) {
+		t.Fatalf("error = %v, want %v", err, ErrInterfaceNotFound)
+	}
+	if !strings.Contains(err.Error(), `"doesnotexist0"`) {
+		t.Errorf("error %q does not name the interface", err)
+	}
+
+	// The available interfaces are listed
+	ifaces, err2 := net.Interfaces()
+	if err2 == nil && len(ifaces) > 0 && !strings.Contains(err.Error(), ifaces[0].Name) {
+		t.Errorf("error %q does not list interface %s", err, ifaces[0].Name)
+	}
+}
+
+func TestSelectIPsIPv6Only(t *testing.T) {
+	linkLocal := &net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}
+	loopback := &net.IPNet{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)}
+
+	// Interfaces with only IPv6 addresses send to ff02::1
+	ipNets, err := selectIPs("eth0", []net.Addr{loopback, linkLocal}, AddressFamilyIPv4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ipNets) != 1 || !ipNets[0].IP.Equal(linkLocal.IP) {
+		t.Fatalf("selectIPs = %v, want %s", ipNets, linkLocal)
+	}
+	tgt, err := interfaceTarget("eth0", ipNets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tgt.dest.Equal(net.IPv6linklocalallnodes) {
+		t.Errorf("target %s, want ff02::1", tgt.dest)
+	}
+
+	// Interfaces without a usable address are skipped
+	for _, addrs := range [][]net.Addr{nil, {loopback}} {
+		if _, err := selectIPs("eth0", addrs, AddressFamilyIPv4); !errors.Is(err, ErrNoSuitableAddress) {
+			t.Errorf("selectIPs(%v) error = %v, want %v", addrs, err, ErrNoSuitableAddress)
+		}
+	}
+	unconfigured := []net.Interface{{Index: 1 << 30, Name: "unconfigured0", Flags: net.FlagUp | net.FlagBroadcast}}
+	if _, err := fanOutTargets(unconfigured, newOptions(nil)); err != nil {
+		t.Errorf("fanOutTargets failed for an interface without address: %v", err)
+	}
+}
+
+func TestSelectIPsDualStack(t *testing.T) {
+	v4 := &net.IPNet{IP: net.IPv4(192, 168, 1, 10).To4(), Mask: net.CIDRMask(24, 32)}
+	v6 := &net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}
+	addrs := []net.Addr{v6, v4}
+
+	tests := []struct {
+		af    AddressFamily
+		want  []net.IP
+		dests []net.IP
+	}{
+		{AddressFamilyIPv4, []net.IP{v4.IP}, []net.IP{net.IPv4(192, 168, 1, 255)}},
+		{AddressFamilyIPv6, []net.IP{v6.IP}, []net.IP{net.IPv6linklocalallnodes}},
+		{AddressFamilyAuto, []net.IP{v4.IP, v6.IP}, []net.IP{net.IPv4(192, 168, 1, 255), net.IPv6linklocalallnodes}},
+	}
+	for _, tt := range tests {
+		ipNets, err := selectIPs("eth0", addrs, tt.af)
+		if err != nil {
+			t.Errorf("selectIPs(%v) failed: %v", tt.af, err)
+			continue
+		}
+		if len(ipNets) != len(tt.want) {
+			t.Errorf("selectIPs(%v) = %v, want %v", tt.af, ipNets, tt.want)
+			continue
+		}
+		for i, ipNet := range ipNets {
+			if !ipNet.IP.Equal(tt.want[i]) {
+				t.Errorf("selectIPs(%v)[%d] = %s, want %s", tt.af, i, ipNet.IP, tt.want[i])
+			}
+			tgt, err := interfaceTarget("eth0", ipNet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tgt.dest.Equal(tt.dests[i]) {
+				t.Errorf("address family %v sends to %s, want %s", tt.af, tgt.dest, tt.dests[i])
+			}
+		}
+	}
+
+	// IPv4 is still used if the interface has no IPv6 address
+	ipNets, err := selectIPs("eth0", []net.Addr{v4}, AddressFamilyIPv6)
+	if err != nil || len(ipNets) != 1 || !ipNets[0].IP.Equal(v4.IP) {
+		t.Errorf("selectIPs(IPv6) of an IPv4-only interface = %v, %v, want %s", ipNets, err, v4.IP)
+	}
+}
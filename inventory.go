@@ -0,0 +1,92 @@
+package goWake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// LoadHosts parses a JSON array of hosts, e.g.
+//
+//	[
+//		{"name": "nas", "mac": "00:11:22:33:44:55", "ip": "192.168.1.10", "port": 7},
+//		{"mac": "66:77:88:99:aa:bb"}
+//	]
+//
+// Only the MAC address is required, and it is validated with `ParseMAC`. Errors
+// report the line and, for invalid hosts, the position of the entry in the array.
+// Hosts listed in YAML are loaded by the hostsyaml subpackage instead.
+func LoadHosts(r io.Reader) ([]Host, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("unable to read hosts"), err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("line %d: hosts must be a JSON array", lineAt(data, dec.InputOffset()))
+	}
+
+	var hosts []Host
+	for entry := 1; dec.More(); entry++ {
+		line := lineAt(data, entryOffset(data, dec.InputOffset()))
+
+		var h Host
+		if err := dec.Decode(&h); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line = lineAt(data, syntaxErr.Offset)
+			}
+			return nil, fmt.Errorf("entry %d (line %d): %w", entry, line, err)
+		}
+		if _, err := ParseMAC(h.MAC); err != nil {
+			return nil, fmt.Errorf("entry %d (line %d): %w", entry, line, err)
+		}
+		hosts = append(hosts, h)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", lineAt(data, dec.InputOffset()), err)
+	}
+	return hosts, nil
+}
+
+// WakeFromFile loads the hosts from the JSON file at path, see `LoadHosts`,
+// and wakes all of them, see `WakeHosts`.
+func WakeFromFile(path string, opts ...Option) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hosts, err := LoadHosts(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return WakeHosts(hosts, opts...)
+}
+
+// lineAt returns the 1-based line of data the byte at offset belongs to.
+func lineAt(data []byte, offset int64) int {
+	offset = min(max(offset, 0), int64(len(data)))
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// entryOffset returns the offset of the first byte of the next array entry at or
+// after offset, skipping whitespace and the separating comma.
+func entryOffset(data []byte, offset int64) int64 {
+	for ; offset < int64(len(data)); offset++ {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+		default:
+			return offset
+		}
+	}
+	return offset
+}
@@ -0,0 +1,110 @@
+package goWake
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadHosts(t *testing.T) {
+	hosts, err := LoadHosts(strings.NewReader(`[
+	{"name": "nas", "mac": "00:11:22:33:44:55", "ip": "192.168.1.10", "port": 7},
+	{"mac": "0011.2233.4466"}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Host{
+		{Name: "nas", MAC: "00:11:22:33:44:55", IP: net.IPv4(192, 168, 1, 10), Port: 7},
+		{MAC: "0011.2233.4466"},
+	}
+	if len(hosts) != len(want) {
+		t.Fatalf("got %d hosts, want %d", len(hosts), len(want))
+	}
+	for i, h := range hosts {
+		if h.Name != want[i].Name || h.MAC != want[i].MAC || !h.IP.Equal(want[i].IP) || h.Port != want[i].Port {
+			t.Errorf("host %d = %+v, want %+v", i, h, want[i])
+		}
+	}
+
+	if hosts, err := LoadHosts(strings.NewReader("[]")); err != nil || len(hosts) != 0 {
+		t.Errorf("LoadHosts([]) = %v, %v, want no hosts", hosts, err)
+	}
+}
+
+func TestLoadHostsInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		json string
+		want string
+	}{
+		{`{"mac": "00:11:22:33:44:55"}`, "line 1: hosts must be a JSON array"},
+		{"", "line 1: hosts must be a JSON array"},
+		{"[\n{\"mac\": \"00:11:22:33:44:55\"},\n{\"mac\": \"00:11:22:33:44:55\", \"user\": \"root\"}\n]", "entry 2 (line 3): json: unknown field \"user\""},
+		{"[\n{\"mac\": \"00:11:22:33:44:55\"},\n\n{\"mac\": \"00:11:22:33:44:55\",}\n]", "entry 2 (line 4)"},
+		{"[\n{\"mac\": \"00:11:22:33:44:55\", \"port\": \"seven\"}]", "entry 1 (line 2)"},
+		{"[\n{\"mac\": \"00:11:22:33:44:55\"}", "line 2"},
+	} {
+		_, err := LoadHosts(strings.NewReader(tt.json))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadHosts(%q) error = %v, want %q", tt.json, err, tt.want)
+		}
+	}
+
+	_, err := LoadHosts(strings.NewReader("[\n{\"mac\": \"00:11:22:33:44:55\"},\n{\"name\": \"nas\", \"mac\": \"00:11:22:33:44\"}\n]"))
+	if !errors.Is(err, ErrInvalidMAC) || !strings.Contains(err.Error(), "entry 2 (line 3)") {
+		t.Errorf("error = %v, want %v in entry 2 (line 3)", err, ErrInvalidMAC)
+	}
+}
+
+func TestWakeFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	inventory := `[
+	{"name": "nas", "mac": "00:11:22:33:44:55", "ip": "192.0.2.10", "port": 7},
+	{"mac": "66:77:88:99:aa:bb"}
+]`
+	if err := os.WriteFile(path, []byte(inventory), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var d fakeDialer
+	if err := WakeFromFile(path, WithDialer(&d), WithBroadcast(testBroadcast), WithConcurrency(1)); err != nil {
+		t.Fatal(err)
+	}
+	var raddrs []string
+	for _, raddr := range d.raddrs {
+		raddrs = append(raddrs, raddr.String())
+	}
+	slices.Sort(raddrs)
+	if want := []string{"192.0.2.10:7", "192.0.2.255:9"}; !slices.Equal(raddrs, want) {
+		t.Errorf("sent to %v, want %v", raddrs, want)
+	}
+	for _, mac := range []string{"00:11:22:33:44:55", "66:77:88:99:aa:bb"} {
+		want, err := PacketBytes(mac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.ContainsFunc(d.dialed(), func(c *fakeConn) bool {
+			packets := c.packets()
+			return len(packets) == 1 && bytes.Equal(packets[0], want)
+		}) {
+			t.Errorf("no magic packet sent for %s", mac)
+		}
+	}
+
+	// Errors name the file
+	if err := os.WriteFile(path, []byte("[{\"mac\": \"00:11\"}]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WakeFromFile(path, WithDialer(&d)); !errors.Is(err, ErrInvalidMAC) || !strings.HasPrefix(err.Error(), path) {
+		t.Errorf("error = %v, want %v prefixed with %s", err, ErrInvalidMAC, path)
+	}
+	if err := WakeFromFile(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("error = %v, want %v", err, os.ErrNotExist)
+	}
+}
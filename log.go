@@ -0,0 +1,29 @@
+package goWake
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Attribute keys used for log records
+const (
+	logKeyMAC       = "mac"
+	logKeyIface     = "iface"
+	logKeyIP        = "ip"
+	logKeyBroadcast = "broadcast"
+	logKeyBytes     = "bytes"
+	logKeyProtocol  = "protocol"
+	logKeyError     = "error"
+	logKeyCount     = "count"
+)
+
+// discardLogger is the default logger, which drops every record.
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is a `slog.Handler` which is never enabled.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -2,44 +2,100 @@ package goWake
 
 import (
 	"bytes"
-	"encoding/binary"
+	"encoding"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net"
-	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Define globals for the MacAddress parsing
 var (
 	delims = ":-"
-	reMAC  = regexp.MustCompile(`^([0-9a-fA-F]{2}[` + delims + `]){5}([0-9a-fA-F]{2})$`)
 )
 
 // MACAddress define construct for MAC Address
 type MACAddress [6]byte
 
+// Number of repetitions of the MAC address in a magic packet, see `WithMACRepeat`.
+// MaxMACRepeat keeps the packet small enough for a single UDP datagram or Ethernet frame.
+const (
+	DefaultMACRepeat = 16
+	MaxMACRepeat     = 240
+)
+
 // A MagicPacket is constituted of 6 bytes of 0xFF followed by
-// 16 groups of the destination MAC address.
+// 16 groups of the destination MAC address and an optional
+// SecureOn password of 4 or 6 bytes.
 type MagicPacket struct {
-	header  [6]byte
-	payload [16]MACAddress
+	header   [6]byte
+	payload  []MACAddress
+	password []byte
 }
 
+// MagicPacket can be used with codecs relying on the standard binary interfaces
+var (
+	_ encoding.BinaryMarshaler   = (*MagicPacket)(nil)
+	_ encoding.BinaryUnmarshaler = (*MagicPacket)(nil)
+)
+
 // NewMagicPacket accepts a MAC address string, and returns a pointer to
 // a MagicPacket object. A magic packet is a broadcast frame which
 // contains 6 bytes of 0xFF followed by 16 repetitions of a given mac address.
-func NewMagicPacket(mac string) (*MagicPacket, error) {
+// If a password is set with `WithPassword`, it is appended after the last
+// repetition of the mac address. Non-standard packets with a different number
+// of repetitions can be built with `WithMACRepeat`.
+// NewMagicPacket never panics: any string which is not a valid MAC address
+// results in an error wrapping `ErrInvalidMAC`.
+// Multicast MAC addresses, such as the broadcast address, result in an error
+// wrapping `ErrMulticastMAC` unless `WithMulticastMAC` is set.
+func NewMagicPacket(mac string, opts ...Option) (*MagicPacket, error) {
+	hwAddr, err := ParseMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	return newMagicPacket(hwAddr, newOptions(opts))
+}
+
+// PacketBytes returns the serialized magic packet for the given MAC address
+// without sending it, e.g. to send it over a custom transport. The packet is
+// 102 bytes long, plus the length of the password set with `WithPassword`,
+// unless the number of repetitions is changed with `WithMACRepeat`.
+func PacketBytes(mac string, opts ...Option) ([]byte, error) {
+	hwAddr, err := ParseMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	return marshalPacket(hwAddr, newOptions(opts))
+}
+
+// WritePacket writes the serialized magic packet for the given MAC address to w,
+// e.g. to pipe it into another tool, and returns the number of bytes written.
+// The packet is the same as returned by `PacketBytes`.
+func WritePacket(w io.Writer, mac string, opts ...Option) (int, error) {
+	data, err := PacketBytes(mac, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(data)
+}
+
+// newMagicPacket builds a magic packet for an already parsed MAC address.
+func newMagicPacket(hwAddr net.HardwareAddr, opt options) (*MagicPacket, error) {
 	var packet MagicPacket
 	var macAddr MACAddress
 
-	// We only support 6 byte MAC addresses since it is much harder to use
-	// the binary.Write(...) interface when the size of the MagicPacket is
-	// dynamic.
-	if !reMAC.MatchString(mac) {
-		return nil, fmt.Errorf("mac address %s is not valid", mac)
+	if len(hwAddr) != len(macAddr) {
+		return nil, macLengthError(hwAddr.String(), len(hwAddr))
 	}
 
-	hwAddr, err := net.ParseMAC(mac)
-	if err != nil {
+	if err := checkUnicastMAC(hwAddr, opt); err != nil {
+		return nil, err
+	}
+
+	if err := validateMACRepeat(opt.macRepeat); err != nil {
 		return nil, err
 	}
 
@@ -53,20 +109,383 @@ func NewMagicPacket(mac string) (*MagicPacket, error) {
 		packet.header[idx] = 0xFF
 	}
 
-	// Setup the payload which is 16 repetitions of the MAC addr
+	// Setup the payload which is 16 repetitions of the MAC addr by default
+	packet.payload = make([]MACAddress, opt.macRepeat)
 	for idx := range packet.payload {
 		packet.payload[idx] = macAddr
 	}
 
+	// Append the SecureOn password, if any
+	if opt.password != nil {
+		if err := validatePassword(opt.password); err != nil {
+			return nil, err
+		}
+		packet.password = append([]byte(nil), opt.password...)
+	}
+
 	return &packet, nil
 }
 
+// checkUnicastMAC returns an error wrapping `ErrMulticastMAC` if the multicast bit of
+// hwAddr is set, which is the case for ff:ff:ff:ff:ff:ff pasted by mistake, unless
+// `WithMulticastMAC` is set, in which case it is only logged. Locally administered
+// addresses are common for virtual machines and only logged.
+func checkUnicastMAC(hwAddr net.HardwareAddr, opt options) error {
+	switch {
+	case hwAddr[0]&0x01 != 0 && !opt.allowMulticastMAC:
+		return fmt.Errorf("%w %s: the MAC address of a network card is never a multicast address", ErrMulticastMAC, hwAddr)
+	case hwAddr[0]&0x01 != 0:
+		opt.logger.Warn("waking multicast mac address", logKeyMAC, hwAddr.String())
+	case hwAddr[0]&0x02 != 0:
+		opt.logger.Debug("waking locally administered mac address", logKeyMAC, hwAddr.String())
+	}
+	return nil
+}
+
 // Marshal serializes the magic packet structure into a byte slice.
 func (mp *MagicPacket) Marshal() ([]byte, error) {
-	var buf bytes.Buffer
-	if err := binary.Write(&buf, binary.BigEndian, mp); err != nil {
+	// Size the buffer up front, so the packet is built with a single allocation
+	data := make([]byte, 0, len(mp.header)+len(mp.payload)*len(MACAddress{})+len(mp.password))
+	data = append(data, mp.header[:]...)
+	for _, mac := range mp.payload {
+		data = append(data, mac[:]...)
+	}
+	data = append(data, mp.password...)
+
+	return data, nil
+}
+
+// String returns a hex dump of the magic packet with one labeled line for the
+// sync header, every repetition of the MAC address and the password, if any:
+//
+//	sync:      ff ff ff ff ff ff
+//	mac 01:    00 11 22 33 44 55
+//	...
+//	mac 16:    00 11 22 33 44 55
+//	password:  aa bb cc dd ee ff
+func (mp *MagicPacket) String() string {
+	const lineLen = 12 + 3*len(MACAddress{})
+	lines := 1 + len(mp.payload)
+	if mp.password != nil {
+		lines++
+	}
+
+	var sb strings.Builder
+	sb.Grow(lines * lineLen)
+
+	sb.WriteString("sync:     ")
+	writeHex(&sb, mp.header[:])
+	for idx := range mp.payload {
+		sb.WriteString("\nmac ")
+		if idx < 9 {
+			sb.WriteByte('0')
+		}
+		label := strconv.Itoa(idx + 1)
+		sb.WriteString(label)
+		sb.WriteByte(':')
+		// Repetitions from 100 on take up one of the spaces
+		sb.WriteString("   "[max(len(label)-2, 0):])
+		writeHex(&sb, mp.payload[idx][:])
+	}
+	if mp.password != nil {
+		sb.WriteString("\npassword: ")
+		writeHex(&sb, mp.password)
+	}
+
+	return sb.String()
+}
+
+// writeHex writes every byte of data as two hex digits preceded by a space.
+func writeHex(sb *strings.Builder, data []byte) {
+	const hexDigits = "0123456789abcdef"
+	for _, b := range data {
+		sb.WriteByte(' ')
+		sb.WriteByte(hexDigits[b>>4])
+		sb.WriteByte(hexDigits[b&0x0f])
+	}
+}
+
+// ParseMAC parses a 6 byte MAC address in any of the following formats,
+// ignoring leading and trailing whitespace and the case of the hex digits:
+//
+//	00:11:22:33:44:55
+//	00-11-22-33-44-55
+//	0011.2233.4455
+//	001122334455
+//
+// It returns an error wrapping `ErrInvalidMAC` if s is not a valid MAC address.
+// Longer hardware addresses such as EUI-64 identifiers or InfiniBand GUIDs are
+// rejected with an error explaining that Wake-on-LAN needs a 6 byte MAC address.
+func ParseMAC(s string) (net.HardwareAddr, error) {
+	mac := strings.TrimSpace(s)
+
+	// Reject anything longer than the longest hardware address format, a 20 byte
+	// InfiniBand address, up front; the parsers below then only ever see short,
+	// bounded input.
+	if len(mac) > len("00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01") {
+		return nil, fmt.Errorf("%w %q", ErrInvalidMAC, truncate(s, 32))
+	}
+
+	var hwAddr net.HardwareAddr
+	var err error
+	if !strings.ContainsAny(mac, delims+".") {
+		hwAddr, err = hex.DecodeString(mac)
+	} else {
+		hwAddr, err = net.ParseMAC(mac)
+	}
+	if err != nil || len(hwAddr) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrInvalidMAC, s)
+	}
+
+	// Network cards only recognize magic packets repeating their 6 byte Ethernet
+	// MAC address, so packets for longer hardware addresses would never wake anything.
+	if len(hwAddr) != len(MACAddress{}) {
+		return nil, macLengthError(s, len(hwAddr))
+	}
+
+	return hwAddr, nil
+}
+
+// CanonicalMAC parses a MAC address in any format accepted by `ParseMAC` and
+// returns it in its canonical form of lowercase hex digits separated by colons,
+// e.g. "00:11:22:33:44:55" for "0011.2233.4455". Addresses with the same canonical
+// form denote the same network card. It returns an error wrapping `ErrInvalidMAC`
+// if s is not a valid MAC address.
+func CanonicalMAC(s string) (string, error) {
+	hwAddr, err := ParseMAC(s)
+	if err != nil {
+		return "", err
+	}
+	return hwAddr.String(), nil
+}
+
+// macLengthError returns an error wrapping `ErrInvalidMAC` for a hardware address
+// of n instead of 6 bytes.
+func macLengthError(s string, n int) error {
+	const hint = "Wake-on-LAN only supports 6 byte Ethernet MAC addresses"
+	switch n {
+	case 8:
+		return fmt.Errorf("%w %q: %s, got an 8 byte EUI-64 identifier", ErrInvalidMAC, s, hint)
+	case 20:
+		return fmt.Errorf("%w %q: %s, got a 20 byte InfiniBand address", ErrInvalidMAC, s, hint)
+	default:
+		return fmt.Errorf("%w %q: %s (got %d bytes)", ErrInvalidMAC, s, hint, n)
+	}
+}
+
+// ParseMACWithPassword parses a MAC address optionally followed by whitespace and
+// a SecureOn password, as accepted by tools like wakeonlan, e.g.
+//
+//	00:11:22:33:44:55 aa-bb-cc-dd-ee-ff
+//
+// The MAC address is parsed with `ParseMAC` and the password with `ParsePassword`,
+// so the returned error wraps `ErrInvalidMAC` or `ErrInvalidPassword` depending on
+// which part is invalid. The password is nil if s only contains a MAC address.
+func ParseMACWithPassword(s string) (net.HardwareAddr, []byte, error) {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 0, 1:
+		hwAddr, err := ParseMAC(s)
+		return hwAddr, nil, err
+	case 2:
+		hwAddr, err := ParseMAC(fields[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		pw, err := ParsePassword(fields[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		return hwAddr, pw, nil
+	default:
+		return nil, nil, fmt.Errorf("%w %q: expected a mac address and an optional password", ErrInvalidMAC, truncate(s, 32))
+	}
+}
+
+// truncate shortens s to at most n bytes for use in error messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// Hex returns the serialized magic packet as a string of lowercase hex digits,
+// e.g. to share it in a bug report. It can be parsed again with `ParseHexPacket`.
+func (mp *MagicPacket) Hex() string {
+	data, _ := mp.Marshal()
+	return hex.EncodeToString(data)
+}
+
+// ParseHexPacket parses a magic packet given as hex digits as returned by Hex,
+// ignoring any whitespace in between, e.g. of a hex dump wrapped over several lines.
+// The decoded packet has to be valid as described for `MagicPacket.Unmarshal`.
+func ParseHexPacket(s string) (*MagicPacket, error) {
+	data, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex packet: %w", err)
+	}
+
+	var packet MagicPacket
+	if err := packet.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return &packet, nil
+}
+
+// Unmarshal parses a serialized magic packet into mp. The data must consist of
+// the 6 byte sync header, 16 repetitions of the same MAC address and an
+// optional SecureOn password of 4 or 6 bytes.
+func (mp *MagicPacket) Unmarshal(data []byte) error {
+	return mp.UnmarshalRepeat(data, DefaultMACRepeat)
+}
+
+// UnmarshalRepeat is like Unmarshal but expects the MAC address to be repeated
+// repeat instead of 16 times, see `WithMACRepeat`.
+func (mp *MagicPacket) UnmarshalRepeat(data []byte, repeat int) error {
+	if err := validateMACRepeat(repeat); err != nil {
+		return err
+	}
+
+	headerLen := len(mp.header)
+	packetLen := headerLen + repeat*len(MACAddress{})
+
+	switch len(data) - packetLen {
+	case 0, 4, 6:
+	default:
+		return fmt.Errorf("%w: got %d bytes", ErrPacketLength, len(data))
+	}
+
+	for _, b := range data[:headerLen] {
+		if b != 0xFF {
+			return ErrSyncHeader
+		}
+	}
+
+	var packet MagicPacket
+	copy(packet.header[:], data[:headerLen])
+	packet.payload = make([]MACAddress, repeat)
+
+	var macAddr MACAddress
+	copy(macAddr[:], data[headerLen:])
+	for idx := range packet.payload {
+		offset := headerLen + idx*len(macAddr)
+		if !bytes.Equal(macAddr[:], data[offset:offset+len(macAddr)]) {
+			return fmt.Errorf("%w: repetition %d differs", ErrMACMismatch, idx)
+		}
+		packet.payload[idx] = macAddr
+	}
+
+	if len(data) > packetLen {
+		packet.password = append([]byte(nil), data[packetLen:]...)
+	}
+
+	*mp = packet
+	return nil
+}
+
+// Validate checks that the magic packet is well-formed: the sync header consists
+// of six 0xFF bytes, the same MAC address is repeated 16 times and the password,
+// if any, is 4 or 6 bytes long. It returns an error wrapping `ErrSyncHeader`,
+// `ErrMACMismatch` or `ErrInvalidPassword` for the first violation found.
+func (mp *MagicPacket) Validate() error {
+	return mp.ValidateRepeat(DefaultMACRepeat)
+}
+
+// ValidateRepeat is like Validate but expects the MAC address to be repeated
+// repeat instead of 16 times, see `WithMACRepeat`. A packet with a different
+// number of repetitions fails with an error wrapping `ErrMACMismatch`.
+func (mp *MagicPacket) ValidateRepeat(repeat int) error {
+	if err := validateMACRepeat(repeat); err != nil {
+		return err
+	}
+
+	for _, b := range mp.header {
+		if b != 0xFF {
+			return ErrSyncHeader
+		}
+	}
+
+	if len(mp.payload) != repeat {
+		return fmt.Errorf("%w: expected %d repetitions (got %d)", ErrMACMismatch, repeat, len(mp.payload))
+	}
+	for idx := range mp.payload {
+		if mp.payload[idx] != mp.payload[0] {
+			return fmt.Errorf("%w: repetition %d differs", ErrMACMismatch, idx)
+		}
+	}
+
+	if mp.password != nil {
+		return validatePassword(mp.password)
+	}
+	return nil
+}
+
+// MarshalBinary implements `encoding.BinaryMarshaler` using Marshal.
+func (mp *MagicPacket) MarshalBinary() ([]byte, error) {
+	return mp.Marshal()
+}
+
+// UnmarshalBinary implements `encoding.BinaryUnmarshaler` using Unmarshal.
+func (mp *MagicPacket) UnmarshalBinary(data []byte) error {
+	return mp.Unmarshal(data)
+}
+
+// MAC returns the destination MAC address of the magic packet.
+func (mp *MagicPacket) MAC() net.HardwareAddr {
+	if len(mp.payload) == 0 {
+		return nil
+	}
+	return append(net.HardwareAddr(nil), mp.payload[0][:]...)
+}
+
+// Password returns the SecureOn password of the magic packet, or nil if none is set.
+func (mp *MagicPacket) Password() []byte {
+	if mp.password == nil {
+		return nil
+	}
+	return append([]byte(nil), mp.password...)
+}
+
+// ParsePassword parses a SecureOn password given in hexadecimal form,
+// either as plain hex digits (`aabbccddeeff`) or as groups of two digits
+// separated by colons or dashes (`aa:bb:cc:dd:ee:ff`, `aa-bb-cc-dd`).
+// The password has to be 4 or 6 bytes long.
+func ParsePassword(s string) ([]byte, error) {
+	hexStr := s
+	if sep := strings.IndexAny(s, delims); sep >= 0 {
+		groups := strings.Split(s, s[sep:sep+1])
+		for _, group := range groups {
+			if len(group) != 2 {
+				return nil, fmt.Errorf("%w %q", ErrInvalidPassword, s)
+			}
+		}
+		hexStr = strings.Join(groups, "")
+	}
+
+	pw, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalidPassword, s)
+	}
+
+	if err := validatePassword(pw); err != nil {
 		return nil, err
 	}
+	return pw, nil
+}
+
+// validatePassword checks that a SecureOn password is 4 or 6 bytes long.
+func validatePassword(pw []byte) error {
+	if len(pw) != 4 && len(pw) != 6 {
+		return fmt.Errorf("%w: must be 4 or 6 bytes long (got %d bytes)", ErrInvalidPassword, len(pw))
+	}
+	return nil
+}
 
-	return buf.Bytes(), nil
+// validateMACRepeat checks that the number of MAC address repetitions is between 1 and `MaxMACRepeat`.
+func validateMACRepeat(repeat int) error {
+	if repeat < 1 || repeat > MaxMACRepeat {
+		return fmt.Errorf("invalid number of mac address repetitions %d: must be between 1 and %d", repeat, MaxMACRepeat)
+	}
+	return nil
 }
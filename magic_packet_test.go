@@ -0,0 +1,211 @@
+package goWake
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseMAC(t *testing.T) {
+	want := net.HardwareAddr{0x00, 0x11, 0x22, 0xaa, 0xbb, 0xcc}
+	for _, s := range []string{
+		"00:11:22:aa:bb:cc",
+		"00:11:22:AA:BB:CC",
+		"00-11-22-aa-bb-cc",
+		"00-11-22-AA-BB-CC",
+		"0011.22aa.bbcc",
+		"0011.22AA.BBCC",
+		"001122aabbcc",
+		"001122AABBCC",
+		"  00:11:22:aa:bb:cc",
+		"00-11-22-aa-bb-cc\n",
+		"\t0011.22aa.bbcc ",
+		" 001122AaBbCc\t",
+	} {
+		hwAddr, err := ParseMAC(s)
+		if err != nil {
+			t.Errorf("ParseMAC(%q): %v", s, err)
+			continue
+		}
+		if !bytes.Equal(hwAddr, want) {
+			t.Errorf("ParseMAC(%q) = %s, want %s", s, hwAddr, want)
+		}
+	}
+}
+
+func TestParseMACInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"00:11:22:33:44",
+		"00:11:22:33:44:gg",
+		"00:11-22:33:44:55",
+		"0011223344",
+		"00112233445",
+		"0011.2233",
+		"00 11 22 33 44 55",
+	} {
+		if _, err := ParseMAC(s); !errors.Is(err, ErrInvalidMAC) {
+			t.Errorf("ParseMAC(%q) error = %v, want %v", s, err, ErrInvalidMAC)
+		}
+	}
+}
+
+func FuzzNewMagicPacket(f *testing.F) {
+	for _, s := range []string{
+		"00:11:22:33:44:55",
+		"00-11-22-33-44-55",
+		"0011.2233.4455",
+		"001122334455",
+		"ff:ff:ff:ff:ff:ff",
+		"00:11:22:33:44:55:66:77",
+		"0011223344556",
+		"00:11:22\x00:33:44:55",
+		"",
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		p, err := NewMagicPacket(s)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidMAC) && !errors.Is(err, ErrMulticastMAC) {
+				t.Fatalf("NewMagicPacket(%q) error = %v, want %v", s, err, ErrInvalidMAC)
+			}
+			return
+		}
+		if err := p.Validate(); err != nil {
+			t.Fatalf("NewMagicPacket(%q) built an invalid packet: %v", s, err)
+		}
+		data, err := p.Marshal()
+		if err != nil || len(data) != 102 {
+			t.Fatalf("NewMagicPacket(%q) marshals to %d bytes (%v), want 102", s, len(data), err)
+		}
+
+		var decoded MagicPacket
+		if err := decoded.Unmarshal(data); err != nil {
+			t.Fatalf("Unmarshal of the packet for %q failed: %v", s, err)
+		}
+		if !bytes.Equal(decoded.MAC(), p.MAC()) || decoded.Password() != nil {
+			t.Fatalf("Unmarshal of the packet for %q = %s, want %s", s, &decoded, p)
+		}
+	})
+}
+
+func TestUnmarshalRoundTrip(t *testing.T) {
+	for _, pw := range [][]byte{nil, {1, 2, 3, 4}, {1, 2, 3, 4, 5, 6}} {
+		p, err := NewMagicPacket("00:11:22:33:44:55", WithPassword(pw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := p.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded MagicPacket
+		if err := decoded.Unmarshal(data); err != nil {
+			t.Fatalf("Unmarshal with password %x failed: %v", pw, err)
+		}
+		if got := decoded.MAC().String(); got != "00:11:22:33:44:55" {
+			t.Errorf("MAC = %s, want 00:11:22:33:44:55", got)
+		}
+		if !bytes.Equal(decoded.Password(), pw) {
+			t.Errorf("password = %x, want %x", decoded.Password(), pw)
+		}
+		if again, err := decoded.Marshal(); err != nil || !bytes.Equal(again, data) {
+			t.Errorf("marshaling the decoded packet gives %x (%v), want %x", again, err, data)
+		}
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	data, err := PacketBytes("00:11:22:33:44:55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := func(idx int) []byte {
+		d := bytes.Clone(data)
+		d[idx] ^= 0x01
+		return d
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrPacketLength},
+		{"truncated", data[:101], ErrPacketLength},
+		{"password of 5 bytes", append(bytes.Clone(data), 1, 2, 3, 4, 5), ErrPacketLength},
+		{"password of 8 bytes", append(bytes.Clone(data), 1, 2, 3, 4, 5, 6, 7, 8), ErrPacketLength},
+		{"sync header", corrupt(2), ErrSyncHeader},
+		{"last repetition", corrupt(101), ErrMACMismatch},
+		{"middle repetition", corrupt(6 + 8*6), ErrMACMismatch},
+	}
+	for _, tt := range tests {
+		var p MagicPacket
+		if err := p.Unmarshal(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Unmarshal error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNewMagicPacketLongAddress(t *testing.T) {
+	for _, s := range []string{
+		"00:11:22:33:44:55:66:77",
+		"0011.2233.4455.6677",
+		"00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01",
+	} {
+		_, err := NewMagicPacket(s)
+		if !errors.Is(err, ErrInvalidMAC) {
+			t.Errorf("NewMagicPacket(%q) error = %v, want %v", s, err, ErrInvalidMAC)
+			continue
+		}
+		if !strings.Contains(err.Error(), "only supports 6 byte Ethernet MAC addresses") {
+			t.Errorf("NewMagicPacket(%q) error %q does not explain the length", s, err)
+		}
+	}
+}
+
+func TestCanonicalMAC(t *testing.T) {
+	for _, s := range []string{
+		"00:1A:2b:3c:4D:5e",
+		"00-1a-2b-3c-4d-5e",
+		"001a.2b3c.4d5e",
+		"001A2B3C4D5E",
+		" 00:1a:2b:3c:4d:5e\n",
+	} {
+		got, err := CanonicalMAC(s)
+		if err != nil {
+			t.Errorf("CanonicalMAC(%q): %v", s, err)
+			continue
+		}
+		if want := "00:1a:2b:3c:4d:5e"; got != want {
+			t.Errorf("CanonicalMAC(%q) = %q, want %q", s, got, want)
+		}
+	}
+
+	if _, err := CanonicalMAC("00:1a:2b"); !errors.Is(err, ErrInvalidMAC) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidMAC)
+	}
+}
+
+func TestNewMagicPacketMulticastMAC(t *testing.T) {
+	for _, mac := range []string{"ff:ff:ff:ff:ff:ff", "01:00:5e:00:00:01"} {
+		if _, err := NewMagicPacket(mac); !errors.Is(err, ErrMulticastMAC) {
+			t.Errorf("NewMagicPacket(%q) error = %v, want %v", mac, err, ErrMulticastMAC)
+		}
+		if _, err := NewMagicPacket(mac, WithMulticastMAC()); err != nil {
+			t.Errorf("NewMagicPacket(%q, WithMulticastMAC()) failed: %v", mac, err)
+		}
+	}
+
+	// Unicast addresses are accepted, locally administered ones included
+	for _, mac := range []string{"00:11:22:33:44:55", "02:11:22:33:44:55"} {
+		if _, err := NewMagicPacket(mac); err != nil {
+			t.Errorf("NewMagicPacket(%q) failed: %v", mac, err)
+		}
+	}
+}
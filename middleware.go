@@ -0,0 +1,22 @@
+package goWake
+
+import "context"
+
+// A WakeFunc sends a magic packet to the specified MAC address.
+// `Waker.Chain` turns a Waker into a WakeFunc.
+type WakeFunc func(ctx context.Context, mac string) error
+
+// A Middleware wraps a WakeFunc with cross-cutting behavior such as logging,
+// metrics or rate limiting, similar to middleware wrapping an http.Handler.
+type Middleware func(next WakeFunc) WakeFunc
+
+// Chain returns a WakeFunc which sends magic packets using `Waker.WakeContext`,
+// wrapped by the given middleware. The first middleware is the outermost one,
+// i.e. it is called first and sees the result of all others.
+func (w *Waker) Chain(mws ...Middleware) WakeFunc {
+	fn := WakeFunc(w.WakeContext)
+	for i := len(mws) - 1; i >= 0; i-- {
+		fn = mws[i](fn)
+	}
+	return fn
+}
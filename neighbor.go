@@ -0,0 +1,49 @@
+package goWake
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// MACFromIP looks up the MAC address of ip in the neighbor table of the operating
+// system, i.e. the ARP cache for IPv4 addresses. It allows to wake a host of which
+// only the IP address is known, as long as it has recently been reachable.
+// Sleeping hosts disappear from the table after a few minutes, so the address is
+// best looked up and stored while the host is still up.
+// It returns an error wrapping `ErrNeighborNotFound` if there is no complete entry for ip.
+func MACFromIP(ip net.IP) (net.HardwareAddr, error) {
+	if ip == nil {
+		return nil, fmt.Errorf("%w: no IP address given", ErrNeighborNotFound)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	hwAddr, err := lookupNeighbor(ip)
+	if err != nil {
+		return nil, err
+	}
+	if hwAddr == nil || bytes.Equal(hwAddr, make(net.HardwareAddr, len(hwAddr))) {
+		return nil, fmt.Errorf("%w: entry for %s is incomplete", ErrNeighborNotFound, ip)
+	}
+	return hwAddr, nil
+}
+
+// parseNeighborMAC parses a MAC address as printed by the neighbor table tools of
+// the operating systems, which may omit leading zeros, e.g. 0:11:2:33:44:55.
+func parseNeighborMAC(s string) (net.HardwareAddr, error) {
+	groups := strings.FieldsFunc(s, func(r rune) bool { return strings.ContainsRune(delims, r) })
+	for i, g := range groups {
+		if len(g) == 1 {
+			groups[i] = "0" + g
+		}
+	}
+
+	hwAddr, err := ParseMAC(strings.Join(groups, ":"))
+	if err != nil {
+		return nil, err
+	}
+	return hwAddr, nil
+}
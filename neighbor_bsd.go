@@ -0,0 +1,40 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package goWake
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// lookupNeighbor parses the output of `arp -n`, which looks like
+// ? (192.168.1.10) at 0:11:22:33:44:55 on en0 ifscope [ethernet]
+// or contains "(incomplete)" instead of the MAC address.
+// IPv6 neighbors are not supported.
+func lookupNeighbor(ip net.IP) (net.HardwareAddr, error) {
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("%w: only IPv4 addresses are supported", ErrNeighborNotFound)
+	}
+
+	// arp exits with an error if there is no entry, so its output is checked first.
+	out, err := exec.Command("arp", "-n", ip.String()).Output()
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		for i := 1; i < len(fields)-1; i++ {
+			if fields[i] != "at" || fields[i-1] != "("+ip.String()+")" {
+				continue
+			}
+			if fields[i+1] == "(incomplete)" {
+				return nil, fmt.Errorf("%w: entry for %s is incomplete", ErrNeighborNotFound, ip)
+			}
+			return parseNeighborMAC(fields[i+1])
+		}
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, fmt.Errorf("unable to read the ARP table: %w", err)
+	}
+
+	return nil, fmt.Errorf("%w: no entry for %s", ErrNeighborNotFound, ip)
+}
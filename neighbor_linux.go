@@ -0,0 +1,69 @@
+package goWake
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// lookupNeighbor reads IPv4 entries from /proc/net/arp and IPv6 entries from the
+// output of `ip -6 neigh`.
+func lookupNeighbor(ip net.IP) (net.HardwareAddr, error) {
+	if ip.To4() == nil {
+		return lookupNeighborIPv6(ip)
+	}
+
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the ARP table: %w", err)
+	}
+	defer f.Close()
+
+	// The table has a header line followed by lines like
+	// 192.168.1.10  0x1  0x2  00:11:22:33:44:55  *  eth0
+	// where a flags value of 0x0 marks an incomplete entry.
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+		if fields[2] == "0x0" {
+			return nil, fmt.Errorf("%w: entry for %s is incomplete", ErrNeighborNotFound, ip)
+		}
+		return parseNeighborMAC(fields[3])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the ARP table: %w", err)
+	}
+
+	return nil, fmt.Errorf("%w: no entry for %s", ErrNeighborNotFound, ip)
+}
+
+// lookupNeighborIPv6 parses lines like
+// fe80::1 dev eth0 lladdr 00:11:22:33:44:55 REACHABLE
+func lookupNeighborIPv6(ip net.IP) (net.HardwareAddr, error) {
+	out, err := exec.Command("ip", "-6", "neigh", "show", ip.String()).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the neighbor table: %w", err)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+		for i := 0; i < len(fields)-1; i++ {
+			if fields[i] == "lladdr" {
+				return parseNeighborMAC(fields[i+1])
+			}
+		}
+		return nil, fmt.Errorf("%w: entry for %s is incomplete", ErrNeighborNotFound, ip)
+	}
+
+	return nil, fmt.Errorf("%w: no entry for %s", ErrNeighborNotFound, ip)
+}
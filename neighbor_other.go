@@ -0,0 +1,14 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package goWake
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// lookupNeighbor is not supported on this platform.
+func lookupNeighbor(ip net.IP) (net.HardwareAddr, error) {
+	return nil, fmt.Errorf("unable to look up %s in the neighbor table: %w", ip, errors.ErrUnsupported)
+}
@@ -0,0 +1,31 @@
+package goWake
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// lookupNeighbor parses the output of `arp -a`, which lists entries like
+// 192.168.1.10          00-11-22-33-44-55     dynamic
+// IPv6 neighbors are not supported.
+func lookupNeighbor(ip net.IP) (net.HardwareAddr, error) {
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("%w: only IPv4 addresses are supported", ErrNeighborNotFound)
+	}
+
+	// arp exits with an error if there is no entry, so its output is checked first.
+	out, err := exec.Command("arp", "-a", ip.String()).Output()
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && ip.Equal(net.ParseIP(fields[0])) {
+			return parseNeighborMAC(fields[1])
+		}
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, fmt.Errorf("unable to read the ARP table: %w", err)
+	}
+
+	return nil, fmt.Errorf("%w: no entry for %s", ErrNeighborNotFound, ip)
+}
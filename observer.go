@@ -0,0 +1,56 @@
+package goWake
+
+import (
+	"net"
+	"time"
+)
+
+// An Observer is notified about every wake request, e.g. to count attempts and
+// failures or to record the round-trip times of the Echo protocol as metrics.
+// The methods may be called concurrently by `WakeAll` and must not block.
+type Observer interface {
+	// WakeAttempted is called before the magic packet is sent to mac.
+	WakeAttempted(mac string)
+
+	// WakeSucceeded is called once the magic packet has been sent to mac,
+	// d being the time it took including retries and awaiting echo replies.
+	WakeSucceeded(mac string, d time.Duration)
+
+	// WakeFailed is called if the magic packet could not be sent to mac.
+	WakeFailed(mac string, err error)
+
+	// EchoReplied is called for every Echo Reply received with the Echo protocol.
+	EchoReplied(mac string, peer net.IP, rtt time.Duration)
+}
+
+// noopObserver is the default Observer which ignores all notifications.
+type noopObserver struct{}
+
+func (noopObserver) WakeAttempted(string)                      {}
+func (noopObserver) WakeSucceeded(string, time.Duration)       {}
+func (noopObserver) WakeFailed(string, error)                  {}
+func (noopObserver) EchoReplied(string, net.IP, time.Duration) {}
+
+// observe calls fn, which sends the magic packet to hwAddr, and notifies the observer
+// set with `WithObserver` about its outcome.
+func observe(opt options, hwAddr net.HardwareAddr, fn func() (*Result, error)) (*Result, error) {
+	mac := hwAddr.String()
+	start := opt.clock.Now()
+	opt.observer.WakeAttempted(mac)
+
+	result, err := fn()
+	if result != nil {
+		for _, a := range result.Attempts {
+			if a.Err == nil && a.Peer != nil {
+				opt.observer.EchoReplied(mac, a.Peer, a.RTT)
+			}
+		}
+	}
+
+	if err != nil {
+		opt.observer.WakeFailed(mac, err)
+	} else {
+		opt.observer.WakeSucceeded(mac, opt.clock.Now().Sub(start))
+	}
+	return result, err
+}
@@ -1,10 +1,170 @@
 package goWake
 
-import "github.com/mitsimi/goWake/v2/protocol"
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+	"golang.org/x/time/rate"
+)
 
 type options struct {
-	protocol protocol.Proto
-	iface    string
+	protocol          protocol.Proto
+	iface             string
+	ifaceIndex        int
+	port              int
+	password          []byte
+	repeat            int
+	repeatDelay       time.Duration
+	retries           int
+	backoff           time.Duration
+	targetIP          net.IP
+	broadcast         net.IP
+	sourceIP          net.IP
+	sourcePort        int
+	allAddresses      bool
+	concurrency       int
+	logger            *slog.Logger
+	dialer            Dialer
+	dryRun            bool
+	writeTimeout      time.Duration
+	waitTimeout       time.Duration
+	pollInterval      time.Duration
+	resolveTTL        time.Duration
+	echoTimeout       time.Duration
+	echoBestEffort    bool
+	macRepeat         int
+	observer          Observer
+	readBufferSize    int
+	broadcasts        []net.IP
+	deadline          time.Time
+	limiter           *rate.Limiter
+	skipLengthCheck   bool
+	progress          func(ProgressEvent)
+	strictBroadcast   bool
+	packetConn        net.PacketConn
+	relay             string
+	allowDuplicates   bool
+	fallbackBroadcast net.IP
+	burst             int
+	validateChecksum  bool
+	stopOnError       bool
+	allowLoopback     bool
+	subnet            string
+	failover          bool
+	addressFamily     AddressFamily
+	allowMulticastMAC bool
+	clock             clock
+}
+
+// newOptions returns the default options with opts applied on top.
+func newOptions(opts []Option) options {
+	opt := options{
+		protocol:       protocol.Discard,
+		iface:          "",
+		port:           9,
+		repeat:         1,
+		burst:          1,
+		backoff:        100 * time.Millisecond,
+		concurrency:    1,
+		logger:         discardLogger,
+		dialer:         netDialer{},
+		waitTimeout:    2 * time.Minute,
+		pollInterval:   5 * time.Second,
+		echoTimeout:    2 * time.Second,
+		macRepeat:      DefaultMACRepeat,
+		observer:       noopObserver{},
+		readBufferSize: 1500,
+		clock:          realClock{},
+	}
+	for _, o := range Defaults() {
+		o(&opt)
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// defaults holds the options set with SetDefaults.
+var defaults struct {
+	mu   sync.RWMutex
+	opts []Option
+}
+
+// SetDefaults sets options applied to every magic packet sent by this package,
+// e.g. to always use a particular interface without passing `WithInterface` to
+// every call. They replace the defaults set by a previous call; SetDefaults without
+// options clears them. The options passed to a function take precedence over the
+// defaults, which in turn take precedence over the built-in defaults. A `Waker`
+// keeps the defaults in place at the time it was created.
+// SetDefaults is safe for concurrent use, but best called once during initialization.
+func SetDefaults(opts ...Option) {
+	defaults.mu.Lock()
+	defer defaults.mu.Unlock()
+	defaults.opts = slices.Clone(opts)
+}
+
+// Defaults returns the options set with `SetDefaults`.
+func Defaults() []Option {
+	defaults.mu.RLock()
+	defer defaults.mu.RUnlock()
+	return slices.Clone(defaults.opts)
+}
+
+// validate checks the options for values which cannot be used for sending.
+func (opt options) validate() error {
+	if opt.port < 0 || opt.port > 65535 {
+		return fmt.Errorf("invalid port %d: must be between 0 and 65535", opt.port)
+	}
+
+	if opt.sourcePort < 0 || opt.sourcePort > 65535 {
+		return fmt.Errorf("invalid source port %d: must be between 0 and 65535", opt.sourcePort)
+	}
+
+	if opt.retries < 0 {
+		return fmt.Errorf("invalid retry count %d: must not be negative", opt.retries)
+	}
+
+	if opt.repeat < 1 {
+		return fmt.Errorf("invalid repeat count %d: must be at least 1", opt.repeat)
+	}
+
+	if opt.burst < 1 {
+		return fmt.Errorf("invalid burst size %d: must be at least 1", opt.burst)
+	}
+
+	if opt.addressFamily < AddressFamilyIPv4 || opt.addressFamily > AddressFamilyAuto {
+		return fmt.Errorf("invalid address family %s", opt.addressFamily)
+	}
+
+	if opt.subnet != "" {
+		if _, err := subnetBroadcast(opt.subnet); err != nil {
+			return err
+		}
+	}
+
+	if opt.pollInterval <= 0 {
+		return fmt.Errorf("invalid poll interval %s: must be positive", opt.pollInterval)
+	}
+
+	if opt.echoTimeout <= 0 {
+		return fmt.Errorf("invalid echo timeout %s: must be positive", opt.echoTimeout)
+	}
+
+	if opt.readBufferSize < minReadBufferSize {
+		return fmt.Errorf("invalid read buffer size %d: must be at least %d bytes", opt.readBufferSize, minReadBufferSize)
+	}
+
+	if opt.concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d: must be at least 1", opt.concurrency)
+	}
+
+	return nil
 }
 
 // Option is a function that modifies the options for sending a magic packet.
@@ -19,8 +179,454 @@ func WithProtocol(proto protocol.Proto) Option {
 }
 
 // WithInterface sets the network interface used for sending the magic packet.
+// IPv6 link-local and multicast destinations, like ff02::1, are scoped to it.
 func WithInterface(iface string) Option {
 	return func(p *options) {
 		p.iface = iface
 	}
 }
+
+// WithLoopbackAllowed allows to send from the loopback address of the interface set
+// with `WithInterface`, e.g. "lo", which is skipped by default. Combined with
+// `WithTargetIP`, this sends the magic packet to a local server on 127.0.0.1, e.g.
+// in tests. Loopback interfaces are still skipped if no interface is set.
+func WithLoopbackAllowed() Option {
+	return func(p *options) {
+		p.allowLoopback = true
+	}
+}
+
+// WithFailoverInterfaces makes sending over the interface set with `WithInterface`
+// fall back to all other interfaces which are up, as if no interface had been set,
+// if it fails, e.g. because the primary interface is sometimes down. The attempts of
+// the `Result` then also report the other interfaces, including the one which succeeded.
+func WithFailoverInterfaces() Option {
+	return func(p *options) {
+		p.failover = true
+	}
+}
+
+// WithAddressFamily selects which addresses of an interface the magic packet is sent
+// from. By default, IPv4 addresses are preferred and the packet is broadcast on their
+// subnet. With `AddressFamilyIPv6`, IPv6 addresses are preferred and the packet is sent
+// to the all-nodes multicast address ff02::1 instead, and with `AddressFamilyAuto`,
+// it is sent over both families on dual-stack interfaces.
+func WithAddressFamily(af AddressFamily) Option {
+	return func(p *options) {
+		p.addressFamily = af
+	}
+}
+
+// WithInterfaceByIndex sets the network interface used for sending the magic packet
+// by its index, which is more stable than its name on some platforms.
+func WithInterfaceByIndex(idx int) Option {
+	return func(p *options) {
+		p.ifaceIndex = idx
+	}
+}
+
+// WithPort sets the UDP destination port used by the Discard protocol.
+// It defaults to 9 and must be in the range 0-65535.
+func WithPort(port int) Option {
+	return func(p *options) {
+		p.port = port
+	}
+}
+
+// WithPortEcho sends the magic packet to UDP port 7, the port of the echo
+// service, which is used by some older network cards and wake-up tools.
+func WithPortEcho() Option {
+	return WithPort(7)
+}
+
+// WithPortDiscard sends the magic packet to UDP port 9, the port of the discard
+// service. This is the default and the port most devices and tools use.
+func WithPortDiscard() Option {
+	return WithPort(9)
+}
+
+// WithPortReserved sends the magic packet to the reserved UDP port 0, which
+// early implementations of the AMD Magic Packet technology used.
+func WithPortReserved() Option {
+	return WithPort(0)
+}
+
+// WithPassword sets the SecureOn password appended to the magic packet.
+// The password has to be 4 or 6 bytes long, see `ParsePassword` to obtain it
+// from its textual form.
+func WithPassword(pw []byte) Option {
+	return func(p *options) {
+		p.password = pw
+	}
+}
+
+// WithMulticastMAC allows to build magic packets for multicast MAC addresses, including
+// the broadcast address ff:ff:ff:ff:ff:ff, which are rejected with an error wrapping
+// `ErrMulticastMAC` by default, e.g. for network cards listening on such an address.
+func WithMulticastMAC() Option {
+	return func(p *options) {
+		p.allowMulticastMAC = true
+	}
+}
+
+// WithMACRepeat sets how many times the MAC address is repeated in the magic packet,
+// e.g. to generate packets for vendor variants or test harnesses. The Wake-on-LAN
+// specification requires 16 repetitions, which is the default; other values have to
+// be between 1 and `MaxMACRepeat`.
+func WithMACRepeat(n int) Option {
+	return func(p *options) {
+		p.macRepeat = n
+	}
+}
+
+// WithRepeat sets how many times the magic packet is sent by the Discard protocol.
+// Sending stops at the first failed write. It defaults to 1.
+func WithRepeat(count int) Option {
+	return func(p *options) {
+		p.repeat = count
+	}
+}
+
+// WithBurst makes the Discard protocol write the magic packet n times back-to-back
+// for every repetition set with `WithRepeat`, e.g. to hit the brief window in which
+// some network cards listen while entering sleep. Unlike the repetitions, which are
+// spaced by `WithRepeatDelay` and each wait for `WithRateLimit`, the packets of a
+// burst are neither delayed nor rate limited. A failed write ends the burst and is
+// retried as a whole with `WithRetries`. It defaults to 1.
+func WithBurst(n int) Option {
+	return func(p *options) {
+		p.burst = n
+	}
+}
+
+// WithStopOnError makes `WakeEvery` stop at the first failed send and return its
+// error, instead of logging it and sending again with the next interval.
+func WithStopOnError() Option {
+	return func(p *options) {
+		p.stopOnError = true
+	}
+}
+
+// WithRepeatDelay sets the delay between repeated sends configured with `WithRepeat`.
+func WithRepeatDelay(d time.Duration) Option {
+	return func(p *options) {
+		p.repeatDelay = d
+	}
+}
+
+// WithRetries sets how many times sending is retried after a transient network error,
+// such as a timeout or a full socket buffer. Permanent errors are never retried.
+// It defaults to 0.
+func WithRetries(n int) Option {
+	return func(p *options) {
+		p.retries = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry configured with `WithRetries`.
+// The delay doubles with every further retry, up to an hour. It defaults to 100ms.
+func WithBackoff(base time.Duration) Option {
+	return func(p *options) {
+		p.backoff = base
+	}
+}
+
+// WithTargetIP sends the magic packet to the given unicast address instead of a
+// broadcast address, e.g. the last known address of the host or a host reachable
+// through a VPN. Since the sleeping host cannot answer ARP requests, this only
+// works if the last-hop router keeps a persistent ARP entry for the target.
+// It takes precedence over `WithBroadcast`.
+func WithTargetIP(ip net.IP) Option {
+	return func(p *options) {
+		p.targetIP = ip
+	}
+}
+
+// WithRateLimit limits how many magic packets are sent per second, including the
+// repetitions set with `WithRepeat` and the packets of parallel sends of `WakeAll`,
+// e.g. to avoid overwhelming the broadcast handling of a switch. Sends beyond the
+// limit wait, or fail if the context is done first. By default, or if perSecond is
+// not positive, the rate is unlimited.
+// The limit is shared by every call the returned option is passed to, so that it
+// also holds across the hosts of `WakeHosts` and with `SetDefaults`.
+func WithRateLimit(perSecond float64) Option {
+	var limiter *rate.Limiter
+	if perSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(perSecond), 1)
+	}
+	return func(p *options) {
+		p.limiter = limiter
+	}
+}
+
+// WithConcurrency sets how many magic packets `WakeAll` sends in parallel.
+// It defaults to 1, sending one magic packet after the other.
+func WithConcurrency(n int) Option {
+	return func(p *options) {
+		p.concurrency = n
+	}
+}
+
+// WithLogger sets the logger used to report the resolved interfaces and broadcast
+// addresses as well as the result of every write at debug level.
+// By default, nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(p *options) {
+		if l == nil {
+			l = discardLogger
+		}
+		p.logger = l
+	}
+}
+
+// WithProgress sets a callback which is called after every attempt at sending a
+// magic packet, i.e. for every interface and broadcast address and every retry,
+// e.g. to show the progress of `WakeAll` in a user interface. The callback is
+// called synchronously from the sending goroutine, possibly in parallel with
+// `WithConcurrency`, so it should return quickly and must be safe for concurrent use.
+func WithProgress(fn func(ev ProgressEvent)) Option {
+	return func(p *options) {
+		p.progress = fn
+	}
+}
+
+// WithObserver sets the Observer notified about every wake request, which allows
+// to feed metrics clients such as Prometheus or OpenTelemetry. By default, no
+// one is notified.
+func WithObserver(o Observer) Option {
+	return func(p *options) {
+		if o == nil {
+			o = noopObserver{}
+		}
+		p.observer = o
+	}
+}
+
+// WithDialer sets the Dialer used to open the sockets for sending magic packets.
+func WithDialer(d Dialer) Option {
+	return func(p *options) {
+		if d == nil {
+			d = netDialer{}
+		}
+		p.dialer = d
+	}
+}
+
+// WithoutLengthCheck makes the Discard protocol treat every write without error as
+// success, even if fewer bytes than the whole magic packet were reported as written.
+// It is an escape hatch for network stacks which report partial writes of datagrams
+// that were actually queued completely; by default, such writes fail with `ErrShortWrite`.
+func WithoutLengthCheck() Option {
+	return func(p *options) {
+		p.skipLengthCheck = true
+	}
+}
+
+// WithAllowDuplicates makes `WakeAll` send a magic packet for every occurrence of
+// a MAC address listed more than once, instead of waking each address only once.
+func WithAllowDuplicates() Option {
+	return func(p *options) {
+		p.allowDuplicates = true
+	}
+}
+
+// WithRelay sends the magic packet over TCP to the `Relay` listening on addr,
+// e.g. "relay.example.com:9009", which broadcasts it on its local network instead.
+// The options of the relay determine how the packet is sent there; locally, only
+// the options building the packet and the timeouts apply.
+func WithRelay(addr string) Option {
+	return func(p *options) {
+		p.relay = addr
+	}
+}
+
+// WithPacketConn makes the Discard protocol write the magic packet to conn with
+// WriteTo instead of dialing a connection of its own, e.g. to reuse a socket bound
+// with special options or inside a network namespace. The destinations are resolved
+// as usual, but `WithSourceIP`, `WithSourcePort` and `WithDialer` have no effect.
+// The connection is never closed, and its write deadline is overwritten.
+func WithPacketConn(conn net.PacketConn) Option {
+	return func(p *options) {
+		p.packetConn = conn
+	}
+}
+
+// WithDryRun resolves the interfaces and broadcast addresses and builds the magic
+// packet as usual, but does not send it. Combined with `WithLogger`, the intended
+// destinations are logged, which allows to validate a configuration up front.
+func WithDryRun() Option {
+	return func(p *options) {
+		p.dryRun = true
+	}
+}
+
+// WithWriteTimeout sets the timeout for writing the magic packet with the Discard protocol.
+// A write which does not complete in time fails with an error satisfying `net.Error`
+// whose Timeout method reports true. By default, writes do not time out.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(p *options) {
+		p.writeTimeout = d
+	}
+}
+
+// WithEchoTimeout sets how long the Echo protocol waits for the Echo Reply, e.g. to
+// allow for high-latency links. It defaults to 2 seconds. A deadline of the context
+// passed to `WakeContext` further caps it.
+func WithEchoTimeout(d time.Duration) Option {
+	return func(p *options) {
+		p.echoTimeout = d
+	}
+}
+
+// WithReadBufferSize sets the size of the buffer Echo Replies are read into with
+// the Echo protocol. Larger replies are truncated, which still allows to match them
+// but cuts off the echoed payload. It defaults to 1500 bytes, the usual MTU, and must
+// be at least 28 bytes.
+func WithReadBufferSize(n int) Option {
+	return func(p *options) {
+		p.readBufferSize = n
+	}
+}
+
+// WithValidateChecksum makes the Echo protocol verify the checksum of received ICMP
+// messages and ignore corrupted ones, so they cannot be mistaken for a reply. ICMPv6
+// checksums are always verified by the operating system. Messages filling the whole
+// buffer set with `WithReadBufferSize` may be truncated, so their checksum cannot be
+// verified and they are accepted regardless. By default, replies are accepted without
+// verifying their checksum.
+func WithValidateChecksum() Option {
+	return func(p *options) {
+		p.validateChecksum = true
+	}
+}
+
+// WithEchoBestEffort makes the Echo protocol accept any Echo Reply received in time,
+// even if its identifier or sequence number does not match the request, e.g. for
+// devices which reply from a different address or mangle the reply. Since raw sockets
+// receive every Echo Reply of the host, replies to unrelated requests may count as well.
+// By default, only the matching reply is accepted.
+func WithEchoBestEffort() Option {
+	return func(p *options) {
+		p.echoBestEffort = true
+	}
+}
+
+// WithDeadline sets an absolute deadline for writing the magic packet with the Discard
+// protocol and for awaiting the Echo Reply with the Echo protocol, e.g. a deadline
+// computed once for a whole batch of wake requests. The earliest of this deadline, the
+// deadline of the context, and the timeouts set with `WithWriteTimeout` and
+// `WithEchoTimeout` applies. By default, there is no deadline.
+func WithDeadline(t time.Time) Option {
+	return func(p *options) {
+		p.deadline = t
+	}
+}
+
+// WithWaitTimeout sets how long `WakeAndWait` waits for the host to come up.
+// It defaults to 2 minutes.
+func WithWaitTimeout(d time.Duration) Option {
+	return func(p *options) {
+		p.waitTimeout = d
+	}
+}
+
+// WithPollInterval sets how often `WakeAndWait` checks whether the host is up.
+// It defaults to 5 seconds.
+func WithPollInterval(d time.Duration) Option {
+	return func(p *options) {
+		p.pollInterval = d
+	}
+}
+
+// WithBroadcast sets the broadcast address the magic packet is sent to instead of
+// computing it from the subnet of the interface, e.g. a directed broadcast address
+// like 192.168.50.255 of a remote subnet. `WithTargetIP` takes precedence over it.
+func WithBroadcast(ip net.IP) Option {
+	return func(p *options) {
+		p.broadcast = ip
+	}
+}
+
+// WithFallbackBroadcast sets the address the magic packet is sent to if no interface
+// is set and none of the interfaces has a broadcast address, instead of the limited
+// broadcast address 255.255.255.255, e.g. in networks filtering the latter.
+func WithFallbackBroadcast(ip net.IP) Option {
+	return func(p *options) {
+		p.fallbackBroadcast = ip
+	}
+}
+
+// WithSubnet sends the magic packet to the directed broadcast address of the subnet
+// given in CIDR notation, e.g. 192.168.50.255 for "192.168.50.0/24", to wake a host
+// in a remote subnet. The packet is routed like a unicast packet, so this only works
+// if the router of the remote subnet forwards directed broadcasts, which most routers
+// refuse by default, e.g. `ip directed-broadcast` on Cisco interfaces. Like the
+// addresses set with `WithBroadcasts`, it is used in addition to `WithBroadcast`.
+func WithSubnet(cidr string) Option {
+	return func(p *options) {
+		p.subnet = cidr
+	}
+}
+
+// WithBroadcasts sends the magic packet to every given broadcast address, e.g. the
+// directed broadcast addresses of all VLANs the host might be on. Sending succeeds as
+// long as one of the addresses has been reached, otherwise the errors of all addresses
+// are joined. Duplicate addresses, also with the one set with `WithBroadcast`, are
+// skipped. `WithTargetIP` takes precedence over it.
+func WithBroadcasts(ips ...net.IP) Option {
+	return func(p *options) {
+		p.broadcasts = append(p.broadcasts, ips...)
+	}
+}
+
+// WithStrictBroadcast checks before sending that the interface set with `WithInterface`,
+// or every interface the packet is sent over by default, is up and supports broadcasting.
+// Instead of failing to send, or falling back to 255.255.255.255 if no interface is
+// suitable, an error wrapping `ErrNoSuitableAddress` explains why each interface was
+// skipped. Likewise, likely wrong broadcast addresses such as the network address
+// of the subnet are skipped instead of only being logged as a warning, and sending
+// fails with `ErrSuspiciousBroadcast` if no other address remains. The checks are
+// not done for a unicast target set with `WithTargetIP`.
+func WithStrictBroadcast() Option {
+	return func(p *options) {
+		p.strictBroadcast = true
+	}
+}
+
+// WithSourceIP sets the local address the magic packet is sent from. It is an
+// alternative to `WithInterface` when the address but not the name of the interface
+// is known. The address has to be assigned to a local interface, whose subnet
+// broadcast address is then used as destination.
+func WithSourceIP(ip net.IP) Option {
+	return func(p *options) {
+		p.sourceIP = ip
+	}
+}
+
+// WithSourcePort sets the local UDP port the magic packet is sent from with the
+// Discard protocol, e.g. to satisfy firewall rules. It defaults to 0, which lets
+// the operating system choose an ephemeral port.
+func WithSourcePort(port int) Option {
+	return func(p *options) {
+		p.sourcePort = port
+	}
+}
+
+// WithAllAddresses sends the magic packet to the subnet broadcast address of every
+// IPv4 address assigned to the interface instead of only the first one, e.g. to
+// reach hosts on the subnet of a secondary address. Without `WithInterface`, this
+// applies to every interface the packet is sent over.
+func WithAllAddresses() Option {
+	return func(p *options) {
+		p.allAddresses = true
+	}
+}
+
+// WithResolveTTL sets how long a `Waker` reuses the resolved interfaces and broadcast
+// addresses before resolving them again. It defaults to 0, which resolves them only
+// once in `NewWaker`.
+func WithResolveTTL(d time.Duration) Option {
+	return func(p *options) {
+		p.resolveTTL = d
+	}
+}
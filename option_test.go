@@ -0,0 +1,49 @@
+package goWake
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSetDefaults(t *testing.T) {
+	t.Cleanup(func() { SetDefaults() })
+
+	SetDefaults(WithPort(7), WithRepeat(3))
+	if opt := newOptions(nil); opt.port != 7 || opt.repeat != 3 {
+		t.Errorf("port %d and repeat %d, want the defaults 7 and 3", opt.port, opt.repeat)
+	}
+	if opt := newOptions([]Option{WithPort(9)}); opt.port != 9 || opt.repeat != 3 {
+		t.Errorf("port %d and repeat %d, want 9 passed to the call and the default 3", opt.port, opt.repeat)
+	}
+
+	SetDefaults()
+	if opt := newOptions(nil); opt.port != 9 || opt.repeat != 1 {
+		t.Errorf("port %d and repeat %d after clearing the defaults, want 9 and 1", opt.port, opt.repeat)
+	}
+}
+
+func TestSetDefaultsConcurrent(t *testing.T) {
+	t.Cleanup(func() { SetDefaults() })
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				SetDefaults(WithPort(1000 + i))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if port := newOptions(nil).port; port != 9 && (port < 1000 || port >= 1008) {
+					t.Errorf("port %d is neither the built-in default nor one set", port)
+					return
+				}
+				_ = Defaults()
+			}
+		}()
+	}
+	wg.Wait()
+}
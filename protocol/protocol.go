@@ -1,9 +1,43 @@
 package protocol
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Protocol defines the available protocols for sending a magic packet.
 type Proto int
 
 const (
-	Discard Proto = iota // UDP-based Discard protocol (port 9)
-	Echo                 // ICMP-based Echo protocol
+	Discard  Proto = iota // UDP-based Discard protocol (port 9)
+	Echo                  // ICMP-based Echo protocol
+	Ethernet              // raw Ethernet frames with EtherType 0x0842 (Linux and BSD, needs raw socket privileges)
+	Auto                  // Echo, falling back to Discard without raw socket privileges
 )
+
+// String returns the lowercase name of the protocol.
+func (p Proto) String() string {
+	switch p {
+	case Discard:
+		return "discard"
+	case Echo:
+		return "echo"
+	case Ethernet:
+		return "ethernet"
+	case Auto:
+		return "auto"
+	default:
+		return fmt.Sprintf("Proto(%d)", int(p))
+	}
+}
+
+// Parse returns the protocol with the given name as returned by String,
+// ignoring case, e.g. "discard" or "Echo".
+func Parse(name string) (Proto, error) {
+	for _, p := range []Proto{Discard, Echo, Ethernet, Auto} {
+		if strings.EqualFold(name, p.String()) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown protocol %q", name)
+}
@@ -0,0 +1,217 @@
+package goWake
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// The relay protocol forwards magic packets over TCP to a `Relay`, which
+// broadcasts them on its local network. Both directions use the same framing:
+// every message is a 2 byte big-endian length followed by that many bytes.
+//
+//	+--------+--------+----------------------+
+//	| length (uint16) | payload (length bytes) |
+//	+--------+--------+----------------------+
+//
+// The client sends a frame holding the serialized magic packet, and the relay
+// answers with a frame which is empty if the packet was sent, or holds the error
+// message otherwise. A connection may carry any number of such exchanges.
+
+// maxRelayFrame is the size of the largest valid magic packet, the longest
+// frame payload a relay accepts.
+const maxRelayFrame = 6 + 6*MaxMACRepeat + 6
+
+// relayTimeout limits an exchange with a relay if no other deadline applies.
+const relayTimeout = 30 * time.Second
+
+// relayIdleTimeout is how long a relay waits for the next frame of a client
+// before closing the connection.
+const relayIdleTimeout = time.Minute
+
+// sendRelay sends the magic packet to the relay set with `WithRelay` and awaits its answer.
+func sendRelay(ctx context.Context, data []byte, t target, opt options) (_ int, err error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", opt.relay)
+	if err != nil {
+		return 0, fmt.Errorf("unable to connect to relay %s: %w", opt.relay, err)
+	}
+	defer closeConn(ctx, conn, &err)
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	deadline := earliest(writeDeadline(ctx, opt), time.Now().Add(relayTimeout))
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	n, err := writeFrame(conn, data)
+	logWrite(opt, t, n, err)
+	if err == nil {
+		var answer []byte
+		if answer, err = readFrame(bufio.NewReader(conn), maxRelayFrame); err == nil && len(answer) > 0 {
+			err = errors.New(string(answer))
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		return n, fmt.Errorf("relay %s: %w", opt.relay, err)
+	}
+	return n, nil
+}
+
+// writeFrame writes payload to w as a length-prefixed frame and returns the
+// number of payload bytes written.
+func writeFrame(w io.Writer, payload []byte) (int, error) {
+	if len(payload) > 0xFFFF {
+		return 0, fmt.Errorf("frame of %d bytes exceeds the maximum of %d bytes", len(payload), 0xFFFF)
+	}
+
+	frame := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(payload)), uint16(len(payload)))
+	frame = append(frame, payload...)
+
+	n, err := writeFull(w, frame)
+	return max(n-2, 0), err
+}
+
+// writeFull writes all of b to w, which unlike a datagram socket may accept only
+// part of it per call, and returns the number of bytes written.
+func writeFull(w io.Writer, b []byte) (int, error) {
+	var written int
+	for written < len(b) {
+		n, err := w.Write(b[written:])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, fmt.Errorf("%w: %d of %d bytes written", ErrShortWrite, written, len(b))
+		}
+	}
+	return written, nil
+}
+
+// readFrame reads a length-prefixed frame from r, rejecting frames longer than limit.
+func readFrame(r io.Reader, limit int) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if int(length) > limit {
+		return nil, fmt.Errorf("frame of %d bytes exceeds the maximum of %d bytes", length, limit)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// A Relay receives magic packets sent with `WithRelay` over TCP and broadcasts them
+// on its local network, which allows to wake hosts at a remote site without a VPN.
+// Received packets are validated before they are sent, so a relay cannot be used
+// to broadcast arbitrary data. Connections on which no frame arrives for a minute
+// are closed.
+type Relay struct {
+	waker       *Waker
+	idleTimeout time.Duration
+}
+
+// NewRelay creates a Relay which sends the received magic packets using the given
+// options like a `Waker`, e.g. over the interface set with `WithInterface`.
+// The magic packets have to repeat the MAC address as set with `WithMACRepeat`.
+func NewRelay(opts ...Option) (*Relay, error) {
+	w, err := NewWaker(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if w.opt.relay != "" {
+		return nil, fmt.Errorf("relay must not forward to another relay")
+	}
+	return &Relay{waker: w, idleTimeout: relayIdleTimeout}, nil
+}
+
+// ListenAndServe listens on the TCP address addr and serves the connections, see Serve.
+func (r *Relay) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return r.Serve(l)
+}
+
+// Serve accepts connections on l and handles each in its own goroutine until
+// l is closed. It always returns a non-nil error.
+func (r *Relay) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go r.handle(conn)
+	}
+}
+
+// handle answers every magic packet received over conn until it is closed or
+// no complete frame arrives within the idle timeout, so that silent clients do
+// not hold on to the connection.
+func (r *Relay) handle(conn net.Conn) {
+	defer conn.Close()
+
+	opt := r.waker.opt
+	reader := bufio.NewReader(conn)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(r.idleTimeout)); err != nil {
+			return
+		}
+		data, err := readFrame(reader, maxRelayFrame)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				opt.logger.Debug("closing idle relay client", logKeyIP, conn.RemoteAddr())
+			} else if !errors.Is(err, io.EOF) {
+				opt.logger.Debug("failed to read from relay client", logKeyIP, conn.RemoteAddr(), logKeyError, err)
+			}
+			return
+		}
+
+		var answer []byte
+		if err := r.forward(data); err != nil {
+			answer = []byte(err.Error())
+			if len(answer) > maxRelayFrame {
+				answer = answer[:maxRelayFrame]
+			}
+		}
+		if _, err := writeFrame(conn, answer); err != nil {
+			return
+		}
+	}
+}
+
+// forward validates the magic packet and sends it to the destinations of the relay.
+func (r *Relay) forward(data []byte) error {
+	var packet MagicPacket
+	if err := packet.UnmarshalRepeat(data, r.waker.opt.macRepeat); err != nil {
+		return err
+	}
+
+	targets, err := r.waker.resolver.resolve()
+	if err != nil {
+		return err
+	}
+
+	_, err = observe(r.waker.opt, packet.MAC(), func() (*Result, error) {
+		return sendPacket(context.Background(), packet.MAC(), data, targets, r.waker.opt)
+	})
+	return err
+}
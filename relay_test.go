@@ -0,0 +1,129 @@
+package goWake
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// startRelay serves a Relay created with opts on a local port and returns its address.
+func startRelay(t *testing.T, opts ...Option) string {
+	t.Helper()
+	r, err := NewRelay(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serveRelay(t, r)
+}
+
+// serveRelay serves r on a local port and returns its address.
+func serveRelay(t *testing.T, r *Relay) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go r.Serve(l)
+	return l.Addr().String()
+}
+
+func TestRelay(t *testing.T) {
+	const mac = "00:11:22:33:44:55"
+	want, err := PacketBytes(mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d fakeDialer
+	addr := startRelay(t, WithDialer(&d), WithBroadcast(testBroadcast))
+
+	summary, err := WakeSummary(mac, WithRelay(addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sent 102 bytes via relay " + addr; summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+
+	conns := d.dialed()
+	if len(conns) != 1 || len(conns[0].packets()) != 1 {
+		t.Fatalf("relay sent over %d connections, want 1", len(conns))
+	}
+	if got := conns[0].packets()[0]; !bytes.Equal(got, want) {
+		t.Errorf("relay sent %x, want %x", got, want)
+	}
+	if got := d.raddrs[0].String(); got != "192.0.2.255:9" {
+		t.Errorf("relay sent to %s, want 192.0.2.255:9", got)
+	}
+}
+
+func TestWriteFrameChunked(t *testing.T) {
+	packet, err := PacketBytes("00:11:22:33:44:55")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &fakeConn{chunk: 7}
+	n, err := writeFrame(conn, packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(packet) {
+		t.Errorf("wrote %d bytes, want %d", n, len(packet))
+	}
+	if got := bytes.Join(conn.packets(), nil); !bytes.Equal(got[2:], packet) || len(got) != 2+len(packet) {
+		t.Errorf("frame = %x, want the length followed by %x", got, packet)
+	}
+	frame, err := readFrame(bytes.NewReader(bytes.Join(conn.packets(), nil)), maxRelayFrame)
+	if err != nil || !bytes.Equal(frame, packet) {
+		t.Errorf("readFrame = %x, %v; want %x", frame, err, packet)
+	}
+
+	// Datagrams are still sent whole or not at all
+	d := fakeDialer{newConn: func() *fakeConn { return &fakeConn{chunk: 7} }}
+	err = Wake("00:11:22:33:44:55", WithDialer(&d), WithBroadcast(testBroadcast))
+	if !errors.Is(err, ErrShortWrite) {
+		t.Errorf("error = %v, want %v", err, ErrShortWrite)
+	}
+}
+
+// zeroWriter accepts no bytes without reporting an error.
+type zeroWriter struct{}
+
+func (zeroWriter) Write(b []byte) (int, error) { return 0, nil }
+
+func TestWriteFrameNoProgress(t *testing.T) {
+	if _, err := writeFrame(zeroWriter{}, []byte("magic")); !errors.Is(err, ErrShortWrite) {
+		t.Errorf("error = %v, want %v", err, ErrShortWrite)
+	}
+}
+
+func TestRelayIdleTimeout(t *testing.T) {
+	r, err := NewRelay(WithDialer(&fakeDialer{}), WithBroadcast(testBroadcast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.idleTimeout = 50 * time.Millisecond
+	addr := serveRelay(t, r)
+
+	// Clients which send nothing or only part of a frame are disconnected
+	for _, sent := range [][]byte{nil, {0}, {0, 102, 0xFF}} {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write(sent); err != nil {
+			t.Fatal(err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if n, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+			t.Errorf("client which sent %x read %d bytes (%v), want the connection closed", sent, n, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package goWake
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// resolver memoizes the targets resolved from a set of options, so that sending
+// repeatedly does not enumerate the network interfaces of the host every time.
+// The cached targets are resolved again once they are older than ttl; a ttl of 0
+// keeps them forever.
+type resolver struct {
+	opt options
+	ttl time.Duration
+
+	mu         sync.Mutex
+	targets    []target
+	resolvedAt time.Time
+}
+
+// newResolver returns a resolver for opt which keeps resolved targets for ttl.
+func newResolver(opt options, ttl time.Duration) *resolver {
+	return &resolver{opt: opt, ttl: ttl}
+}
+
+// resolve returns the cached targets, resolving them first if there are none yet
+// or they have expired. A failed resolution does not replace the cached targets.
+func (r *resolver) resolve() ([]target, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.targets != nil && (r.ttl <= 0 || r.opt.clock.Now().Sub(r.resolvedAt) < r.ttl) {
+		return r.targets, nil
+	}
+
+	targets, err := resolveTargets(r.opt)
+	if err != nil {
+		return nil, err
+	}
+
+	r.targets, r.resolvedAt = targets, r.opt.clock.Now()
+	return targets, nil
+}
+
+// clone returns a resolver for opt which starts out with the targets cached by r.
+func (r *resolver) clone(opt options) *resolver {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &resolver{
+		opt:        opt,
+		ttl:        opt.resolveTTL,
+		targets:    slices.Clone(r.targets),
+		resolvedAt: r.resolvedAt,
+	}
+}
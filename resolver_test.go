@@ -0,0 +1,38 @@
+package goWake
+
+import "testing"
+
+// BenchmarkResolve compares waking with the package functions, which enumerate the
+// network interfaces with several syscalls for every magic packet, to a Waker, which
+// resolves them once.
+func BenchmarkResolve(b *testing.B) {
+	const mac = "00:11:22:33:44:55"
+	var d fakeDialer
+	d.newConn = func() *fakeConn {
+		d.conns = nil // keep the memory of the benchmark constant
+		return &fakeConn{}
+	}
+
+	b.Run("Wake", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if err := Wake(mac, WithDialer(&d)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Waker", func(b *testing.B) {
+		w, err := NewWaker(WithDialer(&d))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for range b.N {
+			if err := w.Wake(mac); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package goWake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+// Result describes the outcome of a wake request.
+type Result struct {
+	// Attempts holds one entry for every interface the magic packet was sent over.
+	Attempts []Attempt `json:"attempts"`
+
+	// DryRun is set if the packet has not actually been sent, see `WithDryRun`.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Duplicates is the number of duplicate MAC addresses skipped by `WakeAllResult`.
+	Duplicates int `json:"duplicates,omitempty"`
+}
+
+// Mode describes how the destination of a magic packet was addressed.
+type Mode int
+
+const (
+	ModeBroadcast Mode = iota // a broadcast address, computed or set with `WithBroadcast`
+	ModeUnicast               // the unicast address set with `WithTargetIP`
+	ModeMulticast             // a multicast address, like ff02::1 for IPv6
+	ModeRelay                 // the relay set with `WithRelay`
+)
+
+// String returns the lowercase name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeBroadcast:
+		return "broadcast"
+	case ModeUnicast:
+		return "unicast"
+	case ModeMulticast:
+		return "multicast"
+	case ModeRelay:
+		return "relay"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// parseMode returns the mode with the given name as returned by String.
+func parseMode(name string) (Mode, error) {
+	for _, m := range []Mode{ModeBroadcast, ModeUnicast, ModeMulticast, ModeRelay} {
+		if name == m.String() {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode %q", name)
+}
+
+// modeOf returns how the packet is addressed when sent to t.
+func modeOf(t target, opt options) Mode {
+	switch {
+	case opt.relay != "":
+		return ModeRelay
+	case t.dest.IsMulticast():
+		return ModeMulticast
+	case opt.targetIP != nil:
+		return ModeUnicast
+	default:
+		return ModeBroadcast
+	}
+}
+
+// ProgressEvent is passed to the callback set with `WithProgress` after every
+// attempt at sending a magic packet.
+type ProgressEvent struct {
+	MAC string // the MAC address the magic packet wakes
+	Attempt
+}
+
+// Attempt describes a single attempt at sending the magic packet.
+type Attempt struct {
+	Interface   string         // name of the interface, empty if the packet was not bound to one
+	Source      net.IP         // the local address sent from, nil if chosen by the operating system
+	Destination net.IP         // the address the packet was sent to
+	Mode        Mode           // how the destination was addressed
+	Protocol    protocol.Proto // the protocol used, see `protocol.Auto`
+	Try         int            // 1 for the first try, incremented with every retry
+	Bytes       int            // number of bytes written
+	Err         error          // nil if the packet was sent successfully
+
+	// The following fields are only set for the Echo protocol, which turns
+	// waking into a reachability probe.
+	Seq  int           // sequence number of the ICMP Echo Request
+	Peer net.IP        // the address the matching Echo Reply came from
+	RTT  time.Duration // round-trip time of the Echo Request
+	TTL  int           // TTL or hop limit of the Echo Reply, 0 if the platform does not report it
+}
+
+// attemptJSON is the JSON representation of an Attempt.
+type attemptJSON struct {
+	Interface   string `json:"interface,omitempty"`
+	Source      net.IP `json:"source,omitempty"`
+	Destination net.IP `json:"destination"`
+	Mode        string `json:"mode,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	Try         int    `json:"try"`
+	Bytes       int    `json:"bytes"`
+	Error       string `json:"error,omitempty"`
+	Seq         int    `json:"seq,omitempty"`
+	Peer        net.IP `json:"peer,omitempty"`
+	RTT         string `json:"rtt,omitempty"`
+	TTL         int    `json:"ttl,omitempty"`
+}
+
+// MarshalJSON implements `json.Marshaler`. The error is represented by its message
+// and the round-trip time as a duration string like "1.5ms", e.g.
+//
+//	{"interface":"eth0","destination":"192.168.1.255","try":1,"bytes":102}
+func (a Attempt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.toJSON())
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`. Errors are restored from their
+// message only, so they no longer match the errors of this package with `errors.Is`.
+func (a *Attempt) UnmarshalJSON(data []byte) error {
+	var v attemptJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	attempt := Attempt{
+		Interface:   v.Interface,
+		Source:      v.Source,
+		Destination: v.Destination,
+		Try:         v.Try,
+		Bytes:       v.Bytes,
+		Seq:         v.Seq,
+		Peer:        v.Peer,
+		TTL:         v.TTL,
+	}
+	if v.Mode != "" {
+		mode, err := parseMode(v.Mode)
+		if err != nil {
+			return err
+		}
+		attempt.Mode = mode
+	}
+	if v.Protocol != "" {
+		proto, err := protocol.Parse(v.Protocol)
+		if err != nil {
+			return err
+		}
+		attempt.Protocol = proto
+	}
+	if v.Error != "" {
+		attempt.Err = errors.New(v.Error)
+	}
+	if v.RTT != "" {
+		rtt, err := time.ParseDuration(v.RTT)
+		if err != nil {
+			return err
+		}
+		attempt.RTT = rtt
+	}
+
+	*a = attempt
+	return nil
+}
+
+// MarshalJSON implements `json.Marshaler` like `Attempt.MarshalJSON`,
+// adding the MAC address.
+func (ev ProgressEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		MAC string `json:"mac"`
+		attemptJSON
+	}{ev.MAC, ev.Attempt.toJSON()})
+}
+
+// toJSON returns the JSON representation of the attempt.
+func (a Attempt) toJSON() attemptJSON {
+	v := attemptJSON{
+		Interface:   a.Interface,
+		Source:      a.Source,
+		Destination: a.Destination,
+		Mode:        a.Mode.String(),
+		Protocol:    a.Protocol.String(),
+		Try:         a.Try,
+		Bytes:       a.Bytes,
+		Seq:         a.Seq,
+		Peer:        a.Peer,
+		TTL:         a.TTL,
+	}
+	if a.Err != nil {
+		v.Error = a.Err.Error()
+	}
+	if a.RTT != 0 {
+		v.RTT = a.RTT.String()
+	}
+	return v
+}
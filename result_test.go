@@ -0,0 +1,99 @@
+package goWake
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+func TestResultJSON(t *testing.T) {
+	result := Result{
+		Attempts: []Attempt{
+			{Interface: "eth0", Source: net.IPv4(192, 168, 1, 10).To4(), Destination: net.IPv4(192, 168, 1, 255).To4(),
+				Protocol: protocol.Discard, Try: 1, Bytes: 102},
+			{Destination: net.IPv4(192, 168, 1, 20).To4(), Mode: ModeUnicast, Protocol: protocol.Echo, Try: 2, Bytes: 110,
+				Err: errors.New("no response received"), Seq: 7, Peer: net.IPv4(192, 168, 1, 20).To4(), RTT: 1500 * time.Microsecond, TTL: 64},
+		},
+		Duplicates: 1,
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"attempts":[` +
+		`{"interface":"eth0","source":"192.168.1.10","destination":"192.168.1.255","mode":"broadcast","protocol":"discard","try":1,"bytes":102},` +
+		`{"destination":"192.168.1.20","mode":"unicast","protocol":"echo","try":2,"bytes":110,"error":"no response received",` +
+		`"seq":7,"peer":"192.168.1.20","rtt":"1.5ms","ttl":64}],"duplicates":1}`
+	if string(data) != want {
+		t.Errorf("JSON =\n%s\nwant\n%s", data, want)
+	}
+
+	var decoded Result
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Attempts) != len(result.Attempts) || decoded.Duplicates != result.Duplicates {
+		t.Fatalf("decoded %+v, want %+v", decoded, result)
+	}
+	for i, a := range decoded.Attempts {
+		w := result.Attempts[i]
+		if (a.Err == nil) != (w.Err == nil) || a.Err != nil && a.Err.Error() != w.Err.Error() {
+			t.Errorf("attempt %d error = %v, want %v", i, a.Err, w.Err)
+		}
+		a.Err, w.Err = nil, nil
+		if !a.Source.Equal(w.Source) || !a.Destination.Equal(w.Destination) || !a.Peer.Equal(w.Peer) {
+			t.Errorf("attempt %d addresses = %+v, want %+v", i, a, w)
+		}
+		a.Source, a.Destination, a.Peer = nil, nil, nil
+		w.Source, w.Destination, w.Peer = nil, nil, nil
+		if !reflect.DeepEqual(a, w) {
+			t.Errorf("attempt %d = %+v, want %+v", i, a, w)
+		}
+	}
+}
+
+func TestResultMode(t *testing.T) {
+	lo := net.IPv4(127, 0, 0, 1)
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		mode Mode
+		dest net.IP
+	}{
+		{"broadcast", []Option{WithBroadcast(testBroadcast)}, ModeBroadcast, testBroadcast},
+		{"unicast", []Option{WithTargetIP(lo), WithBroadcast(testBroadcast)}, ModeUnicast, lo},
+		{"limited broadcast", []Option{WithBroadcast(net.IPv4bcast)}, ModeBroadcast, net.IPv4bcast},
+		{"multicast", []Option{WithBroadcast(net.IPv4(224, 0, 0, 1))}, ModeMulticast, net.IPv4(224, 0, 0, 1)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := WakeResult("00:11:22:33:44:55", append(tt.opts, WithDialer(&fakeDialer{}))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Attempts) != 1 {
+				t.Fatalf("got %d attempts, want 1", len(result.Attempts))
+			}
+			a := result.Attempts[0]
+			if a.Mode != tt.mode || !a.Destination.Equal(tt.dest) {
+				t.Errorf("attempt is %s to %s, want %s to %s", a.Mode, a.Destination, tt.mode, tt.dest)
+			}
+		})
+	}
+
+	t.Run("relay", func(t *testing.T) {
+		addr := startRelay(t, WithDialer(&fakeDialer{}), WithBroadcast(testBroadcast))
+		result, err := WakeResult("00:11:22:33:44:55", WithRelay(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := result.Attempts[0]; a.Mode != ModeRelay {
+			t.Errorf("attempt is %s, want %s", a.Mode, ModeRelay)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package goWake
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// isRetryable reports whether err is a transient network error worth retrying.
+func isRetryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	for _, transient := range transientErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxBackoff is the longest delay between two retries.
+const maxBackoff = time.Hour
+
+// backoff returns the delay before the given retry, doubling base for every
+// retry after the first, but at most maxBackoff.
+func backoff(base time.Duration, try int) time.Duration {
+	d := base
+	for i := 1; i < try && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
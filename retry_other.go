@@ -0,0 +1,7 @@
+//go:build !unix && !windows
+
+package goWake
+
+// transientErrors are the errors of sends which are retried, see `WithRetries`.
+// Only timeouts are retried on platforms without POSIX error numbers.
+var transientErrors []error
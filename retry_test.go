@@ -0,0 +1,84 @@
+package goWake
+
+import (
+	"errors"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRetries(t *testing.T) {
+	var failures int
+	d := fakeDialer{newConn: func() *fakeConn {
+		if failures < 2 {
+			failures++
+			return &fakeConn{writeErr: os.ErrDeadlineExceeded}
+		}
+		return &fakeConn{}
+	}}
+
+	result, err := WakeResult("00:11:22:33:44:55", WithDialer(&d), WithBroadcast(testBroadcast),
+		WithRetries(5), WithBackoff(time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(d.dialed()); got != 3 {
+		t.Errorf("dialed %d connections, want 3", got)
+	}
+	if got := len(result.Attempts); got != 3 {
+		t.Fatalf("got %d attempts, want 3", got)
+	}
+	for i, a := range result.Attempts {
+		if a.Try != i+1 {
+			t.Errorf("attempt %d has try %d", i, a.Try)
+		}
+		if failed := a.Err != nil; failed != (i < 2) {
+			t.Errorf("attempt %d error = %v", i, a.Err)
+		}
+	}
+}
+
+func TestRetriesPermanentError(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	d := fakeDialer{newConn: func() *fakeConn { return &fakeConn{writeErr: errPermanent} }}
+
+	err := Wake("00:11:22:33:44:55", WithDialer(&d), WithBroadcast(testBroadcast),
+		WithRetries(5), WithBackoff(time.Nanosecond))
+	if !errors.Is(err, errPermanent) {
+		t.Fatalf("error = %v, want %v", err, errPermanent)
+	}
+	if got := len(d.dialed()); got != 1 {
+		t.Errorf("dialed %d connections, want 1", got)
+	}
+
+	// Invalid MAC addresses fail before anything is sent
+	err = Wake("00:11:22:33:44", WithDialer(&d), WithRetries(5))
+	if !errors.Is(err, ErrInvalidMAC) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidMAC)
+	}
+	if got := len(d.dialed()); got != 1 {
+		t.Errorf("dialed %d connections, want 1", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		base time.Duration
+		try  int
+		want time.Duration
+	}{
+		{100 * time.Millisecond, 1, 100 * time.Millisecond},
+		{100 * time.Millisecond, 2, 200 * time.Millisecond},
+		{100 * time.Millisecond, 4, 800 * time.Millisecond},
+		{100 * time.Millisecond, 100, maxBackoff},
+		{time.Minute, 1 << 30, maxBackoff},
+		{math.MaxInt64 / 2, 3, maxBackoff},
+		{math.MaxInt64, 1, maxBackoff},
+	} {
+		if got := backoff(tt.base, tt.try); got != tt.want {
+			t.Errorf("backoff(%s, %d) = %s, want %s", tt.base, tt.try, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package goWake
+
+import "syscall"
+
+// transientErrors are the errors of sends which are retried, see `WithRetries`.
+var transientErrors = []error{
+	syscall.ENOBUFS,
+	syscall.EAGAIN,
+	syscall.ECONNREFUSED,
+	syscall.EHOSTUNREACH,
+	syscall.ENETUNREACH,
+}
@@ -0,0 +1,13 @@
+package goWake
+
+import "golang.org/x/sys/windows"
+
+// transientErrors are the errors of sends which are retried, see `WithRetries`.
+// Sockets on Windows report the WSA error codes rather than the POSIX ones.
+var transientErrors = []error{
+	windows.WSAENOBUFS,
+	windows.WSAEWOULDBLOCK,
+	windows.WSAECONNREFUSED,
+	windows.WSAEHOSTUNREACH,
+	windows.WSAENETUNREACH,
+}
@@ -0,0 +1,85 @@
+package goWake
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WakeAt sends a magic packet to the specified MAC address at time t, see `Wake`.
+// It returns immediately; the returned cancel function stops the scheduled wake,
+// and no packet is sent if it is called before t. An error is only returned for
+// an invalid MAC address or invalid options. Errors occurring when the packet is
+// sent are logged with the logger set with `WithLogger` and reported to the
+// observer set with `WithObserver`.
+// WakeAt is meant for simple one-shot schedules within the lifetime of the process,
+// recurring or long-term schedules are better left to cron or systemd timers.
+func WakeAt(t time.Time, mac string, opts ...Option) (cancel func(), err error) {
+	if _, _, err := ParseMACWithPassword(mac); err != nil {
+		return nil, err
+	}
+	opt := newOptions(opts)
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		if err := WakeContextAt(ctx, t, mac, opts...); err != nil && ctx.Err() == nil {
+			opt.logger.Error("scheduled wake failed", logKeyMAC, mac, logKeyError, err)
+		}
+	}()
+	return cancel, nil
+}
+
+// WakeContextAt waits until time t and then sends a magic packet to the specified
+// MAC address, see `WakeContext`. If ctx is done before t, no packet is sent and
+// the context error is returned. A time in the past sends the packet immediately.
+func WakeContextAt(ctx context.Context, t time.Time, mac string, opts ...Option) error {
+	opt := newOptions(opts)
+	hwAddr, err := parseMACArg(mac, &opt)
+	if err != nil {
+		return err
+	}
+
+	if err := sleep(ctx, opt.clock, t.Sub(opt.clock.Now())); err != nil {
+		return err
+	}
+	_, err = wake(ctx, hwAddr, opt)
+	return err
+}
+
+// WakeEvery sends a magic packet to the specified MAC address immediately and then
+// again interval after every send until ctx is done, e.g. to keep a device awake which
+// falls asleep again on its own, and returns the context error. The destinations are
+// resolved once like with a `Waker`. A failed send is logged with the logger set with `WithLogger`
+// and reported to the observer set with `WithObserver`, and sending continues with the
+// next interval unless `WithStopOnError` is set, in which case the error is returned.
+func WakeEvery(ctx context.Context, interval time.Duration, mac string, opts ...Option) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s: must be positive", interval)
+	}
+	if _, _, err := ParseMACWithPassword(mac); err != nil {
+		return err
+	}
+	w, err := NewWaker(opts...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := w.WakeContext(ctx, mac); err != nil && ctx.Err() == nil {
+			if w.opt.stopOnError {
+				return err
+			}
+			w.opt.logger.Warn("periodic wake failed", logKeyMAC, mac, logKeyError, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.opt.clock.After(interval):
+		}
+	}
+}
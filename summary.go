@@ -0,0 +1,85 @@
+package goWake
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+// WakeSummary is like `WakeResult` but describes the outcome in a single line for
+// logs and audit trails, e.g.
+//
+//	sent 102 bytes to 192.168.1.255:9 via eth0, repeated 3×
+//
+// Every destination is described in the order it was sent to, separated by "; ".
+// The summary is also returned if sending failed, as long as it was attempted.
+func WakeSummary(mac string, opts ...Option) (string, error) {
+	opt := newOptions(opts)
+	hwAddr, err := parseMACArg(mac, &opt)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := wake(context.Background(), hwAddr, opt)
+	if result == nil {
+		return "", err
+	}
+	return summarize(result, opt), err
+}
+
+// summarize describes the last attempt for every destination of result.
+func summarize(result *Result, opt options) string {
+	var parts []string
+	for i, a := range result.Attempts {
+		if i+1 < len(result.Attempts) && result.Attempts[i+1].Try > 1 {
+			continue
+		}
+		parts = append(parts, summarizeAttempt(a, opt, result.DryRun))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// summarizeAttempt describes a single attempt.
+func summarizeAttempt(a Attempt, opt options, dryRun bool) string {
+	dest := "to " + a.Destination.String()
+	switch {
+	case a.Mode == ModeRelay:
+		// The relay resolves the destinations on its own
+		dest = "via relay " + opt.relay
+	case a.Protocol == protocol.Discard:
+		dest = "to " + net.JoinHostPort(a.Destination.String(), strconv.Itoa(opt.port))
+	case a.Protocol == protocol.Ethernet:
+		dest = "to " + broadcastMAC.String()
+	}
+	if a.Interface != "" {
+		dest += " via " + a.Interface
+	}
+
+	var sb strings.Builder
+	switch {
+	case dryRun:
+		fmt.Fprintf(&sb, "dry run, not sent %s", dest)
+	case a.Err != nil:
+		fmt.Fprintf(&sb, "failed to send %s: %v", dest, a.Err)
+	case a.Mode != ModeRelay && a.Protocol == protocol.Discard && opt.repeat*opt.burst > 1:
+		sends := opt.repeat * opt.burst
+		fmt.Fprintf(&sb, "sent %d bytes %s, repeated %d×", a.Bytes/sends, dest, sends)
+	default:
+		fmt.Fprintf(&sb, "sent %d bytes %s", a.Bytes, dest)
+	}
+
+	if a.Peer != nil {
+		fmt.Fprintf(&sb, ", reply from %s in %s", a.Peer, a.RTT)
+	}
+	if a.Peer != nil && a.TTL > 0 {
+		fmt.Fprintf(&sb, " with TTL %d", a.TTL)
+	}
+	if a.Try > 1 {
+		fmt.Fprintf(&sb, " after %d tries", a.Try)
+	}
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+package goWake
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mitsimi/goWake/v2/protocol"
+)
+
+func TestWakeSummary(t *testing.T) {
+	summary, err := WakeSummary("00:11:22:33:44:55", WithDialer(&fakeDialer{}), WithBroadcast(testBroadcast), WithRepeat(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sent 102 bytes to 192.0.2.255:9, repeated 3×"; summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	broadcast := net.IPv4(192, 168, 1, 255)
+	for _, tt := range []struct {
+		name     string
+		attempts []Attempt
+		opts     []Option
+		want     string
+	}{
+		{
+			name:     "repeated",
+			attempts: []Attempt{{Interface: "eth0", Destination: broadcast, Protocol: protocol.Discard, Try: 1, Bytes: 306}},
+			opts:     []Option{WithRepeat(3)},
+			want:     "sent 102 bytes to 192.168.1.255:9 via eth0, repeated 3×",
+		},
+		{
+			name: "retried",
+			attempts: []Attempt{
+				{Interface: "eth0", Destination: broadcast, Protocol: protocol.Discard, Try: 1, Err: errors.New("no buffer space")},
+				{Interface: "eth0", Destination: broadcast, Protocol: protocol.Discard, Try: 2, Bytes: 102},
+				{Interface: "wlan0", Destination: net.IPv4(10, 0, 0, 255), Protocol: protocol.Discard, Try: 1, Err: errors.New("network is unreachable")},
+			},
+			want: "sent 102 bytes to 192.168.1.255:9 via eth0 after 2 tries; " +
+				"failed to send to 10.0.0.255:9 via wlan0: network is unreachable",
+		},
+		{
+			name: "echo",
+			attempts: []Attempt{{Destination: net.IPv4(192, 168, 1, 10), Protocol: protocol.Echo, Try: 1, Bytes: 110,
+				Peer: net.IPv4(192, 168, 1, 10), RTT: 3 * time.Millisecond, TTL: 64}},
+			want: "sent 110 bytes to 192.168.1.10, reply from 192.168.1.10 in 3ms with TTL 64",
+		},
+		{
+			name:     "ethernet",
+			attempts: []Attempt{{Interface: "eth0", Protocol: protocol.Ethernet, Try: 1, Bytes: 116}},
+			want:     "sent 116 bytes to ff:ff:ff:ff:ff:ff via eth0",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(&Result{Attempts: tt.attempts}, newOptions(tt.opts))
+			if got != tt.want {
+				t.Errorf("summary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
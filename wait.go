@@ -0,0 +1,80 @@
+package goWake
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// A HostCheck reports whether a host is up by returning nil.
+// It is called repeatedly by `WakeAndWait` until it succeeds and should
+// return as soon as ctx is done.
+type HostCheck func(ctx context.Context) error
+
+// TCPCheck returns a HostCheck which succeeds once a TCP connection to the
+// given port of addr can be established, e.g. port 22 for SSH or 3389 for RDP.
+func TCPCheck(addr string, port int) HostCheck {
+	hostPort := net.JoinHostPort(addr, strconv.Itoa(port))
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", hostPort)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// WakeAndWait sends a magic packet to the specified MAC address like Wake and
+// then polls check until the host is up. It returns the time elapsed from sending
+// the magic packet until check succeeded.
+// The check is repeated every `WithPollInterval` (5 seconds by default), each
+// call being limited to that interval, until `WithWaitTimeout` (2 minutes by
+// default) expires.
+func WakeAndWait(mac string, check HostCheck, opts ...Option) (time.Duration, error) {
+	opt := newOptions(opts)
+	hwAddr, err := parseMACArg(mac, &opt)
+	if err != nil {
+		return 0, err
+	}
+
+	start := opt.clock.Now()
+	if _, err := wake(context.Background(), hwAddr, opt); err != nil {
+		return 0, err
+	}
+
+	// The timeout is measured by the clock like the poll interval.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timeout := opt.clock.After(opt.waitTimeout)
+	go func() {
+		select {
+		case <-timeout:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		checkedAt := opt.clock.Now()
+		checkErr := pollHost(ctx, check, opt.pollInterval)
+		if checkErr == nil {
+			return opt.clock.Now().Sub(start), nil
+		}
+
+		// Checks start every interval, however long the last one took.
+		rest := max(opt.pollInterval-opt.clock.Now().Sub(checkedAt), 0)
+		if err := sleep(ctx, opt.clock, rest); err != nil {
+			return 0, fmt.Errorf("host did not come up within %s: %w", opt.waitTimeout, checkErr)
+		}
+	}
+}
+
+// pollHost runs a single host check limited to the given timeout.
+func pollHost(ctx context.Context, check HostCheck, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return check(ctx)
+}
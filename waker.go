@@ -0,0 +1,92 @@
+package goWake
+
+import (
+	"context"
+	"slices"
+)
+
+// A Waker sends magic packets using a fixed set of options. The network
+// interfaces and broadcast addresses are resolved once by `NewWaker` and
+// reused for every magic packet sent, which makes a Waker the better choice
+// for waking hosts repeatedly. With `WithResolveTTL`, they are resolved again
+// once they are older than the given duration, e.g. to pick up address changes
+// in long-running programs.
+type Waker struct {
+	opt      options
+	resolver *resolver
+	err      error // invalid options passed to With
+}
+
+// NewWaker creates a Waker from the given options. It returns an error if the
+// options are invalid or the destinations of the magic packets cannot be resolved,
+// e.g. because the interface set with `WithInterface` does not exist.
+func NewWaker(opts ...Option) (*Waker, error) {
+	opt := newOptions(opts)
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+
+	r := newResolver(opt, opt.resolveTTL)
+	if _, err := r.resolve(); err != nil {
+		return nil, err
+	}
+
+	return &Waker{opt: opt, resolver: r}, nil
+}
+
+// With returns a copy of w with opts applied on top of its options, e.g. to send with
+// another protocol. The copy reuses the interfaces and broadcast addresses resolved
+// by w, so options affecting them, such as `WithInterface` or `WithBroadcast`, only
+// take effect once they expire with `WithResolveTTL`; use `NewWaker` to apply them
+// right away. If the resulting options are invalid, sending with the copy fails.
+func (w *Waker) With(opts ...Option) *Waker {
+	opt := w.opt
+	opt.password = slices.Clone(opt.password)
+	opt.broadcasts = slices.Clone(opt.broadcasts)
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &Waker{opt: opt, resolver: w.resolver.clone(opt), err: opt.validate()}
+}
+
+// Wake sends a magic packet to the specified MAC address, see `Wake`.
+func (w *Waker) Wake(mac string) error {
+	return w.WakeContext(context.Background(), mac)
+}
+
+// WakeContext is like Wake but honors the cancellation and deadline of ctx, see `WakeContext`.
+func (w *Waker) WakeContext(ctx context.Context, mac string) error {
+	_, err := w.wakeResult(ctx, mac)
+	return err
+}
+
+// wakeResult sends a magic packet to the specified MAC address and returns the result.
+func (w *Waker) wakeResult(ctx context.Context, mac string) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	opt := w.opt
+	hwAddr, err := parseMACArg(mac, &opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return observe(opt, hwAddr, func() (*Result, error) {
+		data, err := marshalPacket(hwAddr, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		targets, err := w.resolver.resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		return sendPacket(ctx, hwAddr, data, targets, opt)
+	})
+}
@@ -1,10 +1,15 @@
 package goWake
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mitsimi/goWake/v2/protocol"
@@ -16,143 +21,883 @@ var (
 
 // Wake sends a magic packet to the specified MAC address to wake up a remote host.
 // It returns an error if the magic packet could not be sent.
-// By default, it uses the UDP-based Discard protocol (port 9) and sends it over all interfaces.
-// The protocol and network interface can be customized using the `WithProtocol` and `WithInterface` options.
+// By default, it uses the UDP-based Discard protocol (port 9) and sends it over all interfaces
+// which are up, to the broadcast address of each interface's subnet. In that case an error is
+// only returned if sending failed on every interface.
+// The protocol, destination port and network interface can be customized using the `WithProtocol`,
+// `WithPort` and `WithInterface` options.
 // If the Echo protocol is used, it will wait for an echo response from the remote host.
+// The MAC address may be followed by a SecureOn password, e.g. "00:11:22:33:44:55 aa-bb-cc-dd".
 func Wake(mac string, opts ...Option) error {
-	opt := options{protocol: protocol.Discard, iface: ""}
-	for _, o := range opts {
-		o(&opt)
+	return WakeContext(context.Background(), mac, opts...)
+}
+
+// WakeContext is like Wake but honors the cancellation and deadline of ctx.
+// A deadline on ctx caps the time the Echo protocol waits for a reply, see `WithEchoTimeout`.
+// If ctx is canceled while a packet is in flight, the connection is closed
+// and the context error is returned.
+func WakeContext(ctx context.Context, mac string, opts ...Option) error {
+	opt := newOptions(opts)
+	hwAddr, err := parseMACArg(mac, &opt)
+	if err != nil {
+		return err
 	}
-	return wake(mac, opt)
+	_, err = wake(ctx, hwAddr, opt)
+	return err
 }
 
-func wake(mac string, opt options) error {
-	var localAddr net.Addr
-	var broadcastAddr net.IP = defaultBroadcast
+// WakeHardwareAddr is like Wake but takes an already parsed MAC address,
+// e.g. one obtained from `net.Interfaces()`. The address must be 6 bytes long.
+func WakeHardwareAddr(addr net.HardwareAddr, opts ...Option) error {
+	_, err := wake(context.Background(), addr, newOptions(opts))
+	return err
+}
+
+// WakeResult is like Wake but additionally returns a `Result` describing the
+// outcome of every send attempt. The result is non-nil whenever sending was
+// attempted, even if an error is returned. With the Echo protocol, the attempts
+// also report the round-trip time and the address of the host which replied.
+func WakeResult(mac string, opts ...Option) (*Result, error) {
+	opt := newOptions(opts)
+	hwAddr, err := parseMACArg(mac, &opt)
+	if err != nil {
+		return nil, err
+	}
+	return wake(context.Background(), hwAddr, opt)
+}
+
+// WakePacket sends the pre-built magic packet p like `Wake`, e.g. a packet built once
+// with `NewMagicPacket` or received with `MagicPacket.Unmarshal`. The options building
+// a packet, such as `WithPassword`, do not apply; instead p is validated to repeat the
+// MAC address as set with `WithMACRepeat`, see `MagicPacket.ValidateRepeat`. Like
+// with `NewMagicPacket`, packets for multicast MAC addresses are rejected unless
+// `WithMulticastMAC` is set.
+func WakePacket(p *MagicPacket, opts ...Option) error {
+	if p == nil {
+		return fmt.Errorf("no magic packet given")
+	}
+
+	opt := newOptions(opts)
+	if err := opt.validate(); err != nil {
+		return err
+	}
+	if err := p.ValidateRepeat(opt.macRepeat); err != nil {
+		return err
+	}
 
-	if iface := opt.iface; iface != "" {
-		ipAddr, err := ipFromInterface(iface)
+	hwAddr := p.MAC()
+	if err := checkUnicastMAC(hwAddr, opt); err != nil {
+		return err
+	}
+	_, err := observe(opt, hwAddr, func() (*Result, error) {
+		data, err := p.Marshal()
 		if err != nil {
-			return errors.Join(fmt.Errorf("unable to get address for interface %s", iface), err)
+			return nil, err
 		}
 
-		localAddr = &net.UDPAddr{IP: ipAddr.IP}
-		broadcastAddr, err = subnetBroadcastIP(ipAddr)
+		targets, err := resolveTargets(opt)
 		if err != nil {
-			return errors.Join(fmt.Errorf("unable to calculate broadcast address for interface %s", iface), err)
+			return nil, err
 		}
+
+		return sendPacket(context.Background(), hwAddr, data, targets, opt)
+	})
+	return err
+}
+
+// parseMACArg parses the MAC address passed to the wake functions, which may be
+// followed by a SecureOn password, see `ParseMACWithPassword`. Such a password
+// replaces the one set with `WithPassword`.
+func parseMACArg(mac string, opt *options) (net.HardwareAddr, error) {
+	hwAddr, pw, err := ParseMACWithPassword(mac)
+	if err != nil {
+		return nil, err
+	}
+	if pw != nil {
+		opt.password = pw
 	}
+	return hwAddr, nil
+}
 
-	switch opt.protocol {
-	case protocol.Discard:
-		return sendUDPDiscard(mac, broadcastAddr, localAddr)
-	case protocol.Echo:
-		return sendICMPEcho(mac, broadcastAddr, localAddr)
-	default:
-		return fmt.Errorf("unsupported protocol")
+func wake(ctx context.Context, hwAddr net.HardwareAddr, opt options) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+
+	return observe(opt, hwAddr, func() (*Result, error) {
+		data, err := marshalPacket(hwAddr, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		targets, err := resolveTargets(opt)
+		if err != nil {
+			return nil, err
+		}
+
+		return sendPacket(ctx, hwAddr, data, targets, opt)
+	})
 }
 
-// sendUDPDiscard sends the magic packet using UDP on the discard protocol (port 9).
-func sendUDPDiscard(mac string, broadcastAddr net.IP, localAddr net.Addr) error {
-	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", broadcastAddr.String(), 9))
+// marshalPacket builds and serializes the magic packet for the given MAC address.
+// The packet is built once per wake and the returned buffer is shared by all
+// repetitions and targets, which may send it concurrently, so it must not be modified.
+func marshalPacket(hwAddr net.HardwareAddr, opt options) ([]byte, error) {
+	packet, err := newMagicPacket(hwAddr, opt)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	conn, err := net.DialUDP("udp", localAddr.(*net.UDPAddr), udpAddr)
+	data, err := packet.Marshal()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer conn.Close()
 
-	packet, err := NewMagicPacket(mac)
-	if err != nil {
-		return err
+	opt.logger.Debug("built magic packet", logKeyMAC, hwAddr.String(), logKeyBytes, len(data))
+	return data, nil
+}
+
+// sendPacket sends the serialized magic packet for hwAddr to every target, retrying
+// transient errors. It only fails if none of the targets could be reached, and with
+// `WithFailoverInterfaces` neither could any other interface.
+func sendPacket(ctx context.Context, hwAddr net.HardwareAddr, data []byte, targets []target, opt options) (*Result, error) {
+	result, err := sendTargets(ctx, hwAddr, data, targets, opt)
+	if err == nil || !opt.failover || ctx.Err() != nil {
+		return result, err
 	}
 
-	data, err := packet.Marshal()
+	primary, nameErr := interfaceName(opt)
+	if nameErr != nil || primary == "" {
+		return result, err
+	}
+
+	// Fan out over all interfaces as if no interface had been set
+	fallback := opt
+	fallback.iface, fallback.ifaceIndex, fallback.sourceIP = "", 0, nil
+	candidates, lookupErr := lookupTargets(fallback)
+	if lookupErr != nil {
+		return result, errors.Join(err, lookupErr)
+	}
+	var others []target
+	for _, t := range candidates {
+		if t.iface != primary {
+			others = append(others, t)
+		}
+	}
+	if len(others) == 0 {
+		return result, err
+	}
+
+	opt.logger.Warn("failing over to other interfaces", logKeyIface, primary, logKeyError, err)
+	failover, failoverErr := sendTargets(ctx, hwAddr, data, others, fallback)
+	result.Attempts = append(result.Attempts, failover.Attempts...)
+	if failoverErr != nil {
+		return result, errors.Join(err, failoverErr)
+	}
+	return result, nil
+}
+
+// sendTargets sends the serialized magic packet for hwAddr to every target, see sendPacket.
+func sendTargets(ctx context.Context, hwAddr net.HardwareAddr, data []byte, targets []target, opt options) (*Result, error) {
+	result := &Result{DryRun: opt.dryRun}
+
+	// The Auto protocol tries Echo and sticks with Discard once raw sockets turn
+	// out to be unavailable.
+	auto := opt.protocol == protocol.Auto
+	if auto {
+		opt.protocol = protocol.Echo
+	}
+
+	var errs []error
+	for _, t := range targets {
+		var err error
+		for try := 1; ; try++ {
+			var n int
+			var reply echoReply
+			n, reply, err = send(ctx, data, t, opt)
+			if auto && errors.Is(err, ErrRawSocketPermission) {
+				opt.logger.Debug("falling back to the Discard protocol", logKeyError, err)
+				opt.protocol, auto = protocol.Discard, false
+				n, reply, err = send(ctx, data, t, opt)
+			}
+			attempt := Attempt{
+				Interface:   t.iface,
+				Source:      t.localIP,
+				Destination: t.dest,
+				Mode:        modeOf(t, opt),
+				Protocol:    opt.protocol,
+				Try:         try,
+				Bytes:       n,
+				Err:         err,
+				Seq:         reply.seq,
+				Peer:        reply.peer,
+				RTT:         reply.rtt,
+				TTL:         reply.ttl,
+			}
+			result.Attempts = append(result.Attempts, attempt)
+			if opt.progress != nil {
+				opt.progress(ProgressEvent{MAC: hwAddr.String(), Attempt: attempt})
+			}
+			if err == nil || try > opt.retries || !isRetryable(err) {
+				break
+			}
+			if err := sleep(ctx, opt.clock, backoff(opt.backoff, try)); err != nil {
+				return result, err
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		switch {
+		case t.iface != "" && t.localIP != nil:
+			err = fmt.Errorf("interface %s (%s): %w", t.iface, t.localIP, err)
+		case t.iface != "":
+			err = fmt.Errorf("interface %s: %w", t.iface, err)
+		case len(targets) > 1:
+			err = fmt.Errorf("destination %s: %w", t.dest, err)
+		}
+		errs = append(errs, err)
+	}
+
+	// Sending succeeded as long as one of the targets has been reached.
+	if len(errs) < len(targets) {
+		return result, nil
+	}
+	return result, errors.Join(errs...)
+}
+
+// target describes a destination for the magic packet and the local address
+// it is sent from.
+type target struct {
+	iface   string     // empty if the packet is not bound to an interface
+	localIP net.IP     // nil lets the operating system choose the source address
+	dest    net.IP     // broadcast address, or the unicast address set with WithTargetIP
+	zone    string     // IPv6 zone of link-local addresses
+	network *net.IPNet // subnet of localIP, nil if unknown
+}
+
+// resolveTargets returns the destinations the magic packet is sent to and logs them.
+func resolveTargets(opt options) ([]target, error) {
+	targets, err := lookupTargets(opt)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	n, err := conn.Write(data)
-	if err == nil && n != 102 {
-		err = fmt.Errorf("magic packet sent was %d bytes (expected 102 bytes)", n)
+	for _, t := range targets {
+		opt.logger.Debug("resolved target", logKeyIface, t.iface, logKeyIP, t.localIP, logKeyBroadcast, t.dest)
 	}
-	return err
+	return checkTargets(targets, opt)
+}
+
+// checkTargets logs the targets with a suspicious broadcast address, see suspiciousBroadcast.
+// With `WithStrictBroadcast`, they are skipped instead, e.g. a single /32 interface among
+// several others, and an error is only returned if no target remains.
+func checkTargets(targets []target, opt options) ([]target, error) {
+	if opt.targetIP != nil {
+		return targets, nil
+	}
+
+	checked := make([]target, 0, len(targets))
+	var errs []error
+	for _, t := range targets {
+		err := suspiciousBroadcast(t)
+		switch {
+		case err == nil:
+			checked = append(checked, t)
+		case opt.strictBroadcast:
+			opt.logger.Warn("skipped suspicious broadcast address", logKeyIface, t.iface, logKeyBroadcast, t.dest, logKeyError, err)
+			errs = append(errs, err)
+		default:
+			opt.logger.Warn("suspicious broadcast address", logKeyIface, t.iface, logKeyBroadcast, t.dest, logKeyError, err)
+			checked = append(checked, t)
+		}
+	}
+
+	if len(checked) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return checked, nil
 }
 
-// sendICMPEcho sends the magic packet using ICMP for the Echo protocol and awaits an answer.
-func sendICMPEcho(mac string, broadcastAddr net.IP, localAddr net.Addr) error {
-	conn, err := net.DialIP("ip4:icmp", localAddr.(*net.IPAddr), &net.IPAddr{IP: broadcastAddr})
+// suspiciousBroadcast returns an error wrapping `ErrSuspiciousBroadcast` if the
+// destination of t is likely not the broadcast address of the subnet it is sent
+// from: the subnet has a /31 or /32 mask without a broadcast address, or the
+// destination is the network address of the subnet.
+func suspiciousBroadcast(t target) error {
+	if t.network == nil || t.dest.To4() == nil {
+		return nil
+	}
+
+	ones, bits := t.network.Mask.Size()
+	if bits == 8*net.IPv4len && ones >= bits-1 {
+		return fmt.Errorf("%w: subnet %s of interface %s has no broadcast address", ErrSuspiciousBroadcast, t.network, t.iface)
+	}
+	if t.dest.Equal(t.network.IP.Mask(t.network.Mask)) {
+		return fmt.Errorf("%w: %s is the network address of subnet %s, not its broadcast address",
+			ErrSuspiciousBroadcast, t.dest, t.network)
+	}
+	return nil
+}
+
+// lookupTargets returns the destinations the magic packet is sent to.
+// If no interface is specified, every interface which is up and has a suitable
+// IP address gets its own target on the interface's subnet broadcast address,
+// or on the all-nodes multicast address for IPv6-only interfaces, see `WithAddressFamily`.
+// If no such interface exists, the packet is sent to 255.255.255.255 instead.
+// A target IP set with `WithTargetIP` or the broadcast addresses set with `WithBroadcast`
+// and `WithBroadcasts` replace the computed broadcast address, the target IP taking precedence.
+// With `WithAllAddresses`, every IPv4 address of an interface gets its own target.
+func lookupTargets(opt options) ([]target, error) {
+	// The relay set with WithRelay resolves the destinations on its own
+	if opt.relay != "" {
+		return []target{{}}, nil
+	}
+
+	dests := destinations(opt)
+
+	iface, ipAddr, err := localAddress(opt)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer conn.Close()
 
-	packet, err := NewMagicPacket(mac)
+	if opt.strictBroadcast && opt.targetIP == nil && iface != "" {
+		if err := checkBroadcast(iface); err != nil {
+			return nil, err
+		}
+	}
+
+	if ipAddr != nil {
+		if len(dests) > 0 {
+			targets := make([]target, 0, len(dests))
+			for _, dest := range dests {
+				local := ipAddr
+				if opt.sourceIP == nil {
+					local = familyIP(iface, dest, ipAddr)
+				}
+				t := target{iface: iface, localIP: local.IP, dest: dest, network: local}
+				if needsZone(t.dest) || needsZone(t.localIP) {
+					t.zone = zoneOf(iface)
+				}
+				targets = append(targets, t)
+			}
+			return targets, nil
+		}
+
+		if opt.allAddresses && opt.sourceIP == nil {
+			if targets := allAddressTargets(iface); len(targets) > 0 {
+				return targets, nil
+			}
+		}
+
+		t, err := interfaceTarget(iface, ipAddr)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("unable to calculate broadcast address for interface %s", iface), err)
+		}
+		if opt.addressFamily == AddressFamilyAuto && opt.sourceIP == nil {
+			if targets := dualStackTargets(iface); len(targets) > 0 {
+				return targets, nil
+			}
+		}
+		return []target{t}, nil
+	}
+
+	if len(dests) > 0 {
+		targets := make([]target, 0, len(dests))
+		for _, dest := range dests {
+			if needsZone(dest) {
+				return nil, fmt.Errorf("%w: %s is only valid on a specific interface, see WithInterface", ErrNoSuitableAddress, dest)
+			}
+			targets = append(targets, target{dest: dest})
+		}
+		return targets, nil
+	}
+
+	ifaces, err := net.Interfaces()
 	if err != nil {
-		return err
+		return nil, errors.Join(fmt.Errorf("unable to list network interfaces"), err)
 	}
+	return fanOutTargets(ifaces, opt)
+}
 
-	data, err := packet.Marshal()
+// fanOutTargets returns a target for every interface among ifaces which is up and has
+// a suitable IP address, or the fallback broadcast address if there is none, see lookupTargets.
+func fanOutTargets(ifaces []net.Interface, opt options) ([]target, error) {
+	var targets []target
+	var skipped []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if opt.strictBroadcast {
+			if reason := broadcastIncapability(&iface); reason != "" {
+				skipped = append(skipped, iface.Name+": "+reason)
+				continue
+			}
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		if opt.allAddresses {
+			if ifaceTargets := allAddressTargets(iface.Name); len(ifaceTargets) > 0 {
+				targets = append(targets, ifaceTargets...)
+				continue
+			}
+		}
+
+		// Interfaces without a usable address are common and skipped, whereas
+		// other errors are unexpected and therefore logged
+		ipAddrs, err := interfaceIPs(&iface, opt.addressFamily)
+		if errors.Is(err, ErrNoSuitableAddress) {
+			opt.logger.Debug("skipped interface without suitable address", logKeyIface, iface.Name)
+			skipped = append(skipped, iface.Name+": no suitable address")
+			continue
+		}
+		if err != nil {
+			opt.logger.Warn("skipped interface", logKeyIface, iface.Name, logKeyError, err)
+			skipped = append(skipped, iface.Name+": "+err.Error())
+			continue
+		}
+
+		ifaceTargets := addressTargets(iface.Name, ipAddrs)
+		if len(ifaceTargets) == 0 {
+			skipped = append(skipped, iface.Name+": no broadcast address")
+			continue
+		}
+		targets = append(targets, ifaceTargets...)
+	}
+
+	if len(targets) == 0 && opt.strictBroadcast {
+		return nil, fmt.Errorf("%w: no interface can broadcast (%s)", ErrNoSuitableAddress, strings.Join(skipped, "; "))
+	}
+	if len(targets) == 0 {
+		fallback := net.IP(defaultBroadcast)
+		if opt.fallbackBroadcast != nil {
+			fallback = opt.fallbackBroadcast
+		}
+		targets = append(targets, target{dest: fallback})
+	}
+	return targets, nil
+}
+
+// checkBroadcast returns an error if the named interface cannot broadcast, see `WithStrictBroadcast`.
+func checkBroadcast(name string) error {
+	iface, err := net.InterfaceByName(name)
 	if err != nil {
-		return err
+		return interfaceNotFound(fmt.Sprintf("%q", name), err)
 	}
+	if reason := broadcastIncapability(iface); reason != "" {
+		return fmt.Errorf("%w: interface %s cannot broadcast: %s", ErrNoSuitableAddress, name, reason)
+	}
+	return nil
+}
 
-	// Send the packet over ICMP
-	if _, err := conn.Write(data); err != nil {
-		return err
+// broadcastIncapability returns why the interface cannot broadcast, or an empty
+// string if it is up and supports broadcasting.
+func broadcastIncapability(iface *net.Interface) string {
+	switch {
+	case iface.Flags&net.FlagUp == 0:
+		return "not up"
+	case iface.Flags&net.FlagBroadcast == 0:
+		return "no broadcast support"
+	default:
+		return ""
+	}
+}
+
+// destinations returns the target IP set with `WithTargetIP` or, if there is
+// none, the broadcast addresses set with `WithBroadcast`, `WithBroadcasts` and `WithSubnet`
+// without duplicates.
+func destinations(opt options) []net.IP {
+	if opt.targetIP != nil {
+		return []net.IP{opt.targetIP}
+	}
+
+	var dests []net.IP
+	seen := make(map[string]bool)
+	candidates := append([]net.IP{opt.broadcast}, opt.broadcasts...)
+	if opt.subnet != "" {
+		// The subnet has been checked by validate
+		if ip, err := subnetBroadcast(opt.subnet); err == nil {
+			candidates = append(candidates, ip)
+		}
+	}
+	for _, ip := range candidates {
+		if ip == nil || seen[ip.String()] {
+			continue
+		}
+		seen[ip.String()] = true
+		dests = append(dests, ip)
 	}
+	return dests
+}
 
-	// Wait for an echo response
-	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
-	reply := make([]byte, 1024)
-	n, err := conn.Read(reply)
+// familyIP returns an address of the named interface of the same family as dest,
+// e.g. an IPv6 address for sending to ff02::1, or ipAddr if there is none.
+func familyIP(name string, dest net.IP, ipAddr *net.IPNet) *net.IPNet {
+	wantIPv6 := dest.To4() == nil
+	if (ipAddr.IP.To4() == nil) == wantIPv6 {
+		return ipAddr
+	}
+
+	af := AddressFamilyIPv4
+	if wantIPv6 {
+		af = AddressFamilyIPv6
+	}
+	if alt, err := ipFromInterface(name, af); err == nil && (alt.IP.To4() == nil) == wantIPv6 {
+		return alt
+	}
+	return ipAddr
+}
+
+// subnetBroadcast returns the directed broadcast address of the subnet given in
+// CIDR notation, see `WithSubnet`.
+func subnetBroadcast(cidr string) (net.IP, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
-		return fmt.Errorf("no response received: %v", err)
+		return nil, fmt.Errorf("invalid subnet %q: %w", cidr, err)
+	}
+	if ipNet.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid subnet %q: IPv6 has no broadcast addresses", cidr)
 	}
+	ip, err := SubnetBroadcast(ipNet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet %q: %w", cidr, err)
+	}
+	return ip, nil
+}
 
-	if !bytes.Equal(data, reply[:n]) {
-		return fmt.Errorf("received response does not match the sent packet")
+// localAddress returns the interface and address to send from as set with `WithSourceIP`
+// or `WithInterface`. It returns a nil address if neither option is set.
+func localAddress(opt options) (string, *net.IPNet, error) {
+	name, err := interfaceName(opt)
+	if err != nil {
+		return "", nil, err
 	}
 
-	return nil
+	if opt.sourceIP != nil {
+		iface, ipAddr, err := interfaceByIP(opt.sourceIP)
+		if err != nil {
+			return "", nil, err
+		}
+		if name != "" && name != iface.Name {
+			return "", nil, fmt.Errorf("%w: source address %s is assigned to interface %s, not %s",
+				ErrNoSuitableAddress, opt.sourceIP, iface.Name, name)
+		}
+		return iface.Name, ipAddr, nil
+	}
+
+	if iface := name; iface != "" {
+		ipAddr, err := ipFromInterface(iface, opt.addressFamily)
+		if errors.Is(err, ErrNoSuitableAddress) && opt.allowLoopback {
+			ipAddr, err = loopbackIP(iface)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return iface, ipAddr, nil
+	}
+
+	return "", nil, nil
 }
 
-// ipFromInterface returns a `*net.IPNet` from a network interface name.
-func ipFromInterface(name string) (*net.IPNet, error) {
+// allAddressTargets returns a target on the subnet broadcast address of every
+// IPv4 address of the named interface.
+func allAddressTargets(name string) []target {
 	iface, err := net.InterfaceByName(name)
 	if err != nil {
-		return nil, err
+		return nil
 	}
 
 	addrs, err := iface.Addrs()
-	if err != nil || len(addrs) == 0 {
-		return nil, fmt.Errorf("no address associated with interface %s", iface.Name)
+	if err != nil {
+		return nil
+	}
+	return addressTargets(name, ipv4Addrs(addrs))
+}
+
+// dualStackTargets returns a target on the subnet broadcast address of the first
+// IPv4 address and one on ff02::1 for the first IPv6 address of the named interface.
+func dualStackTargets(name string) []target {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil
 	}
 
-	for _, addr := range addrs {
-		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
-			return ipNet, nil
+	ipNets, err := interfaceIPs(iface, AddressFamilyAuto)
+	if err != nil {
+		return nil
+	}
+	return addressTargets(name, ipNets)
+}
+
+// addressTargets returns a target for every given address of the named interface,
+// skipping addresses whose broadcast address cannot be computed.
+func addressTargets(name string, ipNets []*net.IPNet) []target {
+	var targets []target
+	for _, ipNet := range ipNets {
+		if t, err := interfaceTarget(name, ipNet); err == nil {
+			targets = append(targets, t)
 		}
 	}
+	return targets
+}
+
+// interfaceName returns the name of the interface set with `WithInterface` or
+// `WithInterfaceByIndex`, or an empty string if neither option is set.
+func interfaceName(opt options) (string, error) {
+	if opt.ifaceIndex == 0 {
+		return opt.iface, nil
+	}
 
-	return nil, fmt.Errorf("no suitable IP address found for interface %s", iface.Name)
+	iface, err := net.InterfaceByIndex(opt.ifaceIndex)
+	if err != nil {
+		return "", interfaceNotFound(fmt.Sprintf("index %d", opt.ifaceIndex), err)
+	}
+	if opt.iface != "" && opt.iface != iface.Name {
+		return "", fmt.Errorf("interface index %d refers to %s, not %s", opt.ifaceIndex, iface.Name, opt.iface)
+	}
+	return iface.Name, nil
 }
 
-// subnetBroadcastIP calculates the broadcast address of the given `*net.IPNet`.
-func subnetBroadcastIP(ipnet *net.IPNet) (net.IP, error) {
-	byteIp := []byte(ipnet.IP)
-	byteMask := []byte(ipnet.Mask)
-	broadcastIP := make([]byte, len(byteIp))
+// interfaceTarget returns the target for sending over the named interface from the given address.
+// IPv4 packets are sent to the subnet broadcast address, IPv6 packets to the link-local all-nodes
+// multicast address ff02::1 scoped to the interface.
+func interfaceTarget(name string, ipAddr *net.IPNet) (target, error) {
+	if ipAddr.IP.To4() == nil {
+		return target{iface: name, localIP: ipAddr.IP, dest: net.IPv6linklocalallnodes, zone: zoneOf(name)}, nil
+	}
+
+	broadcastAddr, err := SubnetBroadcast(ipAddr)
+	if err != nil {
+		return target{}, err
+	}
+	return target{iface: name, localIP: ipAddr.IP, dest: broadcastAddr, network: ipAddr}, nil
+}
 
-	for i := range byteIp {
-		invertedMask := byteMask[i] ^ 0xff
-		broadcastIP[i] = byteIp[i]&byteMask[i] | invertedMask
+// zoneOf returns the IPv6 zone of the named interface, which is its index. Unlike
+// the name, the index is resolved without a lookup per send and also identifies
+// interfaces whose names are not valid zones, such as those on Windows.
+// The name is returned if the interface cannot be found.
+func zoneOf(name string) string {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return name
 	}
+	return strconv.Itoa(iface.Index)
+}
 
-	return broadcastIP, nil
+// needsZone reports whether ip is an IPv6 address that is only valid together with a zone.
+func needsZone(ip net.IP) bool {
+	return ip.To4() == nil && (ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast())
+}
+
+// udpAddr returns the UDP destination address of the target, e.g. `192.168.1.255:9` or `[ff02::1%eth0]:9`.
+func (t target) udpAddr(port int) *net.UDPAddr {
+	return &net.UDPAddr{IP: t.dest, Port: port, Zone: t.zone}
+}
+
+// send sends the serialized magic packet to a single target using the configured protocol.
+// It returns the number of bytes written and, for the Echo protocol, the matching reply.
+func send(ctx context.Context, data []byte, t target, opt options) (int, echoReply, error) {
+	if opt.dryRun {
+		opt.logger.Debug("dry run, not sending magic packet", logKeyProtocol, opt.protocol, logKeyIface, t.iface,
+			logKeyBroadcast, t.dest, logKeyBytes, len(data))
+		return 0, echoReply{}, nil
+	}
+
+	if opt.relay != "" {
+		n, err := sendRelay(ctx, data, t, opt)
+		return n, echoReply{}, err
+	}
+
+	var n int
+	var err error
+	switch opt.protocol {
+	case protocol.Discard:
+		n, err = sendUDPDiscard(ctx, data, t, opt)
+	case protocol.Echo:
+		return sendICMPEcho(ctx, data, t, opt)
+	case protocol.Ethernet:
+		n, err = sendRawEthernet(ctx, data, t, opt)
+	default:
+		err = fmt.Errorf("%w %s", ErrUnsupportedProtocol, opt.protocol)
+	}
+	return n, echoReply{}, err
+}
+
+// sendUDPDiscard sends the magic packet using UDP to the configured port (9 for the discard protocol).
+// The packet is written as many times as configured with `WithRepeat`, stopping at the first error.
+// If the target has no local IP, the source address is chosen by the operating system.
+func sendUDPDiscard(ctx context.Context, data []byte, t target, opt options) (_ int, err error) {
+	if pc := opt.packetConn; pc != nil {
+		// The connection is owned by the caller, so it is not closed but
+		// canceled writes are interrupted through the write deadline.
+		stop := context.AfterFunc(ctx, func() { pc.SetWriteDeadline(time.Now()) })
+		defer stop()
+
+		return writeRepeated(ctx, data, t, opt, packetConnWriter{pc, t.udpAddr(opt.port)})
+	}
+
+	var localAddr *net.UDPAddr
+	if t.localIP != nil || opt.sourcePort != 0 {
+		localAddr = &net.UDPAddr{IP: t.localIP, Port: opt.sourcePort, Zone: t.zone}
+	}
+
+	conn, err := opt.dialer.DialUDP("udp", localAddr, t.udpAddr(opt.port))
+	if err != nil {
+		if opt.sourcePort != 0 {
+			return 0, fmt.Errorf("unable to bind source port %d: %w", opt.sourcePort, err)
+		}
+		return 0, err
+	}
+	defer closeConn(ctx, conn, &err)
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	return writeRepeated(ctx, data, t, opt, conn)
+}
+
+// closeConn closes conn and joins the error closing it, which may reveal a failed
+// send, with *err. Connections closed early because ctx is done are skipped.
+func closeConn(ctx context.Context, conn io.Closer, err *error) {
+	if closeErr := conn.Close(); closeErr != nil && ctx.Err() == nil && !errors.Is(closeErr, net.ErrClosed) {
+		*err = errors.Join(*err, fmt.Errorf("unable to close connection: %w", closeErr))
+	}
+}
+
+// deadlineWriter is the part of a connection needed for writing the magic packet.
+type deadlineWriter interface {
+	Write(b []byte) (int, error)
+	SetWriteDeadline(t time.Time) error
+}
+
+// packetConnWriter writes to addr over an unconnected `net.PacketConn`.
+type packetConnWriter struct {
+	net.PacketConn
+	addr net.Addr
+}
+
+func (w packetConnWriter) Write(b []byte) (int, error) {
+	return w.WriteTo(b, w.addr)
+}
+
+// writeRepeated writes the magic packet to w as many times as configured with
+// `WithRepeat`, stopping at the first error.
+func writeRepeated(ctx context.Context, data []byte, t target, opt options, w deadlineWriter) (int, error) {
+	var written int
+	for i := 0; i < opt.repeat; i++ {
+		if i > 0 && opt.repeatDelay > 0 {
+			if err := sleep(ctx, opt.clock, opt.repeatDelay); err != nil {
+				return written, err
+			}
+		}
+
+		if err := waitRateLimit(ctx, opt); err != nil {
+			return written, err
+		}
+
+		if err := w.SetWriteDeadline(writeDeadline(ctx, opt)); err != nil {
+			return written, err
+		}
+
+		// The packets of a burst are written back-to-back without any delay
+		for range opt.burst {
+			n, err := w.Write(data)
+			written += n
+			logWrite(opt, t, n, err)
+			if err != nil && ctx.Err() != nil {
+				return written, ctx.Err()
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				err = fmt.Errorf("write timed out: %w", err)
+			} else if err != nil {
+				err = broadcastError(err)
+			}
+			if expectedLen := len(data); err == nil && n != expectedLen && !opt.skipLengthCheck {
+				err = fmt.Errorf("%w: magic packet sent was %d bytes (expected %d bytes)", ErrShortWrite, n, expectedLen)
+			}
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// writeDeadline returns the deadline for a write starting now, which is the earliest
+// of the context deadline, the deadline set with `WithDeadline` and the timeout set
+// with `WithWriteTimeout`. The zero time means no deadline. It is passed to the
+// socket and therefore measured in real time rather than by the clock.
+func writeDeadline(ctx context.Context, opt options) time.Time {
+	var timeout time.Time
+	if opt.writeTimeout > 0 {
+		timeout = time.Now().Add(opt.writeTimeout)
+	}
+	ctxDeadline, _ := ctx.Deadline()
+	return earliest(ctxDeadline, opt.deadline, timeout)
+}
+
+// waitRateLimit blocks until the rate limit set with `WithRateLimit` allows to
+// send another packet. It returns the context error if ctx is done first.
+func waitRateLimit(ctx context.Context, opt options) error {
+	if opt.limiter == nil {
+		return ctx.Err()
+	}
+	if err := opt.limiter.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("rate limit: %w", err)
+	}
+	return nil
+}
+
+// earliest returns the earliest of the given deadlines, ignoring zero times.
+// It returns the zero time if all deadlines are zero.
+func earliest(deadlines ...time.Time) time.Time {
+	var deadline time.Time
+	for _, d := range deadlines {
+		if !d.IsZero() && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+	}
+	return deadline
+}
+
+// logWrite logs the result of writing the magic packet to a target. Since it is
+// called for every packet written, it returns early if debug logging is disabled,
+// which saves boxing the attributes.
+func logWrite(opt options, t target, n int, err error) {
+	if !opt.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	if err != nil {
+		opt.logger.Debug("failed to send magic packet", logKeyProtocol, opt.protocol, logKeyIface, t.iface,
+			logKeyBroadcast, t.dest, logKeyBytes, n, logKeyError, err)
+		return
+	}
+	opt.logger.Debug("sent magic packet", logKeyProtocol, opt.protocol, logKeyIface, t.iface,
+		logKeyBroadcast, t.dest, logKeyBytes, n)
+}
+
+// sleep pauses for the given duration of clk or until ctx is done, whichever happens first.
+func sleep(ctx context.Context, clk clock, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clk.After(d):
+		return nil
+	}
 }
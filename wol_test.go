@@ -0,0 +1,409 @@
+package goWake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWakeWithoutOptions(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("Wake panicked: %v", r)
+		}
+	}()
+
+	// Sending may fail without a network, but must not panic
+	if err := Wake("00:11:22:33:44:55"); err != nil {
+		t.Logf("Wake: %v", err)
+	}
+}
+
+func TestMulticastAddress(t *testing.T) {
+	tgt := target{iface: "eth0", dest: net.IPv6linklocalallnodes, zone: "eth0"}
+	if got, want := tgt.udpAddr(9).String(), "[ff02::1%eth0]:9"; got != want {
+		t.Errorf("udpAddr = %s, want %s", got, want)
+	}
+
+	// IPv6 addresses of an interface are sent to ff02::1 scoped to the interface
+	ifaces, err := net.Interfaces()
+	if err != nil || len(ifaces) == 0 {
+		t.Skip("no network interfaces")
+	}
+	iface := ifaces[0]
+	tgt, err = interfaceTarget(iface.Name, &net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("[ff02::1%%%d]:9", iface.Index)
+	if got := tgt.udpAddr(9).String(); got != want {
+		t.Errorf("udpAddr = %s, want %s", got, want)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	d := fakeDialer{newConn: func() *fakeConn { return &fakeConn{block: true} }}
+
+	start := time.Now()
+	err := Wake("00:11:22:33:44:55", WithDialer(&d), WithBroadcast(testBroadcast),
+		WithWriteTimeout(20*time.Millisecond))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("error = %v, want a timeout", err)
+	}
+
+	deadline := d.dialed()[0].writeDeadline()
+	if deadline.Before(start.Add(20*time.Millisecond)) || deadline.After(time.Now()) {
+		t.Errorf("write deadline %s is not 20ms after %s", deadline, start)
+	}
+}
+
+func TestAllAddresses(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.IPv4(192, 168, 1, 10), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("fd00::2"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.IPv4(10, 0, 0, 5), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(8, 32)},
+	}
+	targets := addressTargets("eth0", ipv4Addrs(addrs))
+	if len(targets) != 2 {
+		t.Fatalf("got %d targets, want 2", len(targets))
+	}
+	for i, want := range []string{"192.168.1.255", "10.255.255.255"} {
+		if got := targets[i].dest.String(); got != want {
+			t.Errorf("target %d is %s, want %s", i, got, want)
+		}
+	}
+
+	// Errors are reported per address
+	errFailed := errors.New("failed")
+	d := fakeDialer{dialErr: func(network string, laddr net.IP) error { return errFailed }}
+	opt := newOptions([]Option{WithDialer(&d)})
+	_, err := sendTargets(context.Background(), net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}, nil, targets, opt)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("error = %v, want %v", err, errFailed)
+	}
+	for _, want := range []string{"interface eth0 (192.168.1.10)", "interface eth0 (10.0.0.5)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestStrictBroadcast(t *testing.T) {
+	for _, tt := range []struct {
+		flags net.Flags
+		want  string
+	}{
+		{net.FlagUp | net.FlagBroadcast, ""},
+		{net.FlagUp | net.FlagPointToPoint, "no broadcast support"},
+		{net.FlagBroadcast, "not up"},
+	} {
+		iface := &net.Interface{Name: "tun0", Flags: tt.flags}
+		if got := broadcastIncapability(iface); got != tt.want {
+			t.Errorf("broadcastIncapability(%s) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+
+	// Loopback interfaces do not support broadcasting
+	lo := loopbackInterface(t)
+	err := Wake("00:11:22:33:44:55", WithInterface(lo.Name), WithLoopbackAllowed(), WithStrictBroadcast(),
+		WithDialer(&fakeDialer{}))
+	if !errors.Is(err, ErrNoSuitableAddress) || !strings.Contains(err.Error(), "no broadcast support") {
+		t.Errorf("error = %v, want %v explaining the missing broadcast support", err, ErrNoSuitableAddress)
+	}
+}
+
+// loopbackInterface returns the loopback interface of the host, skipping the test if there is none.
+func loopbackInterface(t *testing.T) net.Interface {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			return iface
+		}
+	}
+	t.Skip("no loopback interface")
+	return net.Interface{}
+}
+
+func TestWithPacketConn(t *testing.T) {
+	const mac = "00:11:22:33:44:55"
+	want, err := PacketBytes(mac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := newFakePacketConn()
+	var d fakeDialer
+	if err := Wake(mac, WithPacketConn(conn), WithDialer(&d), WithBroadcast(testBroadcast), WithRepeat(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	packets := conn.packets()
+	if len(packets) != 2 {
+		t.Fatalf("wrote %d packets, want 2", len(packets))
+	}
+	for i, p := range packets {
+		if !bytes.Equal(p.data, want) || p.addr.String() != "192.0.2.255:9" {
+			t.Errorf("packet %d is %x to %s, want %x to 192.0.2.255:9", i, p.data, p.addr, want)
+		}
+	}
+	if conn.isClosed() {
+		t.Error("connection of the caller closed")
+	}
+	if len(d.dialed()) != 0 {
+		t.Error("dialed a connection despite WithPacketConn")
+	}
+}
+
+func TestSuspiciousBroadcast(t *testing.T) {
+	subnet := func(cidr string) *net.IPNet {
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ipNet.IP = ip
+		return ipNet
+	}
+	ok := target{iface: "eth0", dest: net.IPv4(192, 168, 1, 255), network: subnet("192.168.1.10/24")}
+	slash31 := target{iface: "ptp0", dest: net.IPv4(10, 0, 0, 1), network: subnet("10.0.0.0/31")}
+	slash32 := target{iface: "tun0", dest: net.IPv4(10, 8, 0, 1), network: subnet("10.8.0.1/32")}
+	network := target{iface: "eth1", dest: net.IPv4(192, 168, 2, 0), network: subnet("192.168.2.10/24")}
+
+	if err := suspiciousBroadcast(ok); err != nil {
+		t.Errorf("suspiciousBroadcast(%s): %v", ok.dest, err)
+	}
+	for _, tgt := range []target{slash31, slash32, network} {
+		if err := suspiciousBroadcast(tgt); !errors.Is(err, ErrSuspiciousBroadcast) {
+			t.Errorf("suspiciousBroadcast(%s) = %v, want %v", tgt.network, err, ErrSuspiciousBroadcast)
+		}
+	}
+
+	// Without WithStrictBroadcast, suspicious targets are only logged
+	all := []target{slash31, ok, slash32}
+	targets, err := checkTargets(all, newOptions(nil))
+	if err != nil || len(targets) != 3 {
+		t.Errorf("checkTargets = %d targets, %v; want 3 targets", len(targets), err)
+	}
+
+	// With it, they are skipped, and only fail if no other target remains
+	strict := newOptions([]Option{WithStrictBroadcast()})
+	targets, err = checkTargets(all, strict)
+	if err != nil || len(targets) != 1 || targets[0].iface != "eth0" {
+		t.Errorf("checkTargets = %v, %v; want only eth0", targets, err)
+	}
+	_, err = checkTargets([]target{slash31, slash32}, strict)
+	if !errors.Is(err, ErrSuspiciousBroadcast) || !strings.Contains(err.Error(), "ptp0") || !strings.Contains(err.Error(), "tun0") {
+		t.Errorf("checkTargets error = %v, want %v for ptp0 and tun0", err, ErrSuspiciousBroadcast)
+	}
+}
+
+func TestFallbackBroadcast(t *testing.T) {
+	// An interface which is up but has no address, as its index does not exist
+	unconfigured := []net.Interface{{Index: 1 << 30, Name: "unconfigured0", Flags: net.FlagUp | net.FlagBroadcast}}
+
+	targets, err := fanOutTargets(unconfigured, newOptions(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || !targets[0].dest.Equal(net.IPv4bcast) {
+		t.Errorf("targets = %v, want 255.255.255.255", targets)
+	}
+
+	fallback := net.IPv4(192, 168, 1, 255)
+	targets, err = fanOutTargets(unconfigured, newOptions([]Option{WithFallbackBroadcast(fallback)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || !targets[0].dest.Equal(fallback) || targets[0].iface != "" {
+		t.Errorf("targets = %v, want %s", targets, fallback)
+	}
+
+	// The fallback is not used with an interface
+	lo := loopbackInterface(t)
+	targets, err = lookupTargets(newOptions([]Option{WithInterface(lo.Name), WithLoopbackAllowed(), WithFallbackBroadcast(fallback)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tgt := range targets {
+		if tgt.dest.Equal(fallback) {
+			t.Errorf("sent to the fallback %s over interface %s", fallback, lo.Name)
+		}
+	}
+}
+
+// BenchmarkWakeRepeat shows that the magic packet is built once per wake, so sending
+// it repeatedly does not allocate more per repetition.
+func BenchmarkWakeRepeat(b *testing.B) {
+	for _, repeat := range []int{1, 16} {
+		b.Run(fmt.Sprintf("repeat=%d", repeat), func(b *testing.B) {
+			var d fakeDialer
+			d.newConn = func() *fakeConn {
+				d.conns = nil // keep the memory of the benchmark constant
+				return &fakeConn{discard: true}
+			}
+			w, err := NewWaker(WithDialer(&d), WithBroadcast(testBroadcast), WithRepeat(repeat))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := w.Wake("00:11:22:33:44:55"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestIPv6Zone(t *testing.T) {
+	// Link-local destinations need an interface
+	err := Wake("00:11:22:33:44:55", WithDialer(&fakeDialer{}), WithBroadcast(net.IPv6linklocalallnodes))
+	if !errors.Is(err, ErrNoSuitableAddress) {
+		t.Errorf("error = %v, want %v", err, ErrNoSuitableAddress)
+	}
+
+	iface := ipv6Interface(t)
+	var d fakeDialer
+	if err := Wake("00:11:22:33:44:55", WithDialer(&d), WithInterface(iface.Name), WithAddressFamily(AddressFamilyIPv6)); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.raddrs) != 1 {
+		t.Fatalf("dialed %d connections, want 1", len(d.raddrs))
+	}
+	raddr := d.raddrs[0]
+	if !raddr.IP.Equal(net.IPv6linklocalallnodes) || raddr.Zone != strconv.Itoa(iface.Index) {
+		t.Errorf("sent to %s, want ff02::1 scoped to interface %s (index %d)", raddr, iface.Name, iface.Index)
+	}
+}
+
+// ipv6Interface returns an interface which is up and has a non-loopback IPv6 address,
+// skipping the test if there is none.
+func ipv6Interface(t *testing.T) net.Interface {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if ipNets, err := interfaceIPs(&iface, AddressFamilyIPv6); err == nil && ipNets[0].IP.To4() == nil {
+			return iface
+		}
+	}
+	t.Skip("no interface with an IPv6 address")
+	return net.Interface{}
+}
+
+func TestInterfaceErrors(t *testing.T) {
+	targets := []target{
+		{iface: "eth0", localIP: net.IPv4(192, 168, 1, 10), dest: net.IPv4(192, 168, 1, 255)},
+		{iface: "wlan0", dest: net.IPv4(10, 0, 0, 255)},
+	}
+	errDown := errors.New("network is down")
+	d := fakeDialer{dialErr: func(network string, laddr net.IP) error { return errDown }}
+
+	result, err := sendTargets(context.Background(), net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}, nil, targets,
+		newOptions([]Option{WithDialer(&d)}))
+	if !errors.Is(err, errDown) {
+		t.Fatalf("error = %v, want %v", err, errDown)
+	}
+	for _, want := range []string{"interface eth0 (192.168.1.10): network is down", "interface wlan0: network is down"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	for i, a := range result.Attempts {
+		if a.Interface != targets[i].iface || !errors.Is(a.Err, errDown) {
+			t.Errorf("attempt %d = %+v, want the error of interface %s", i, a, targets[i].iface)
+		}
+	}
+}
+
+func TestWakePacket(t *testing.T) {
+	p, err := NewMagicPacket("00:11:22:33:44:55", WithPassword([]byte{1, 2, 3, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d fakeDialer
+	if err := WakePacket(p, WithDialer(&d), WithBroadcast(testBroadcast)); err != nil {
+		t.Fatal(err)
+	}
+	if packets := d.dialed()[0].packets(); len(packets) != 1 || !bytes.Equal(packets[0], want) {
+		t.Errorf("sent %x, want %x", packets, want)
+	}
+
+	if err := WakePacket(nil, WithDialer(&d)); err == nil {
+		t.Error("WakePacket(nil) succeeded")
+	}
+
+	var broadcast MagicPacket
+	data := bytes.Repeat([]byte{0xff}, 102)
+	if err := broadcast.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := WakePacket(&broadcast, WithDialer(&d), WithBroadcast(testBroadcast)); !errors.Is(err, ErrMulticastMAC) {
+		t.Errorf("error = %v, want %v", err, ErrMulticastMAC)
+	}
+	if err := WakePacket(&broadcast, WithDialer(&d), WithBroadcast(testBroadcast), WithMulticastMAC()); err != nil {
+		t.Errorf("WakePacket with WithMulticastMAC: %v", err)
+	}
+}
+
+func TestFailoverInterfaces(t *testing.T) {
+	lo := loopbackInterface(t)
+	var d fakeDialer
+	d.dialErr = func(network string, laddr net.IP) error {
+		if laddr.IsLoopback() {
+			return errors.New("network is down")
+		}
+		return nil
+	}
+	opts := []Option{WithDialer(&d), WithInterface(lo.Name), WithLoopbackAllowed()}
+
+	if _, err := WakeResult("00:11:22:33:44:55", opts...); err == nil {
+		t.Fatal("no error sending over the failing interface without failover")
+	}
+
+	result, err := WakeResult("00:11:22:33:44:55", append(opts, WithFailoverInterfaces())...)
+	if len(result.Attempts) == 0 {
+		t.Fatal(err)
+	}
+	if a := result.Attempts[0]; a.Interface != lo.Name || a.Err == nil {
+		t.Errorf("first attempt %+v, want a failure over %s", a, lo.Name)
+	}
+	var sent []string
+	for _, a := range result.Attempts[1:] {
+		if a.Interface == lo.Name {
+			t.Errorf("failed over to the primary interface: %+v", a)
+		}
+		if a.Err == nil {
+			sent = append(sent, a.Interface)
+		}
+	}
+	if len(sent) == 0 {
+		t.Skipf("no other interface to fail over to: %v", err)
+	}
+	if err != nil {
+		t.Errorf("failover over %v succeeded but returned %v", sent, err)
+	}
+}
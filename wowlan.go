@@ -0,0 +1,48 @@
+package goWake
+
+// WoWLAN describes whether a network interface can wake hosts while it is asleep,
+// which wireless interfaces only can with Wake on Wireless LAN (WoWLAN) support.
+type WoWLAN int
+
+const (
+	WoWLANUnknown     WoWLAN = iota // support could not be determined
+	WoWLANWired                     // not a wireless interface, WoWLAN does not apply
+	WoWLANSupported                 // wireless interface advertising WoWLAN support
+	WoWLANUnsupported               // wireless interface without WoWLAN support
+)
+
+// String returns a lowercase description of the WoWLAN support.
+func (w WoWLAN) String() string {
+	switch w {
+	case WoWLANWired:
+		return "wired"
+	case WoWLANSupported:
+		return "supported"
+	case WoWLANUnsupported:
+		return "unsupported"
+	default:
+		return "unknown"
+	}
+}
+
+// WakeCapableInterfaces is like `ListWakeInterfaces` but determines the WoWLAN
+// support of every interface and skips wireless interfaces which do not support
+// WoWLAN, e.g. the Wi-Fi interface of most laptops. Interfaces whose support
+// cannot be determined are kept and marked `WoWLANUnknown`; this is the case for
+// all interfaces on platforms other than Linux, and for wireless interfaces on
+// Linux if the iw tool is not installed.
+func WakeCapableInterfaces() ([]WakeInterface, error) {
+	ifaces, err := ListWakeInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var capable []WakeInterface
+	for _, iface := range ifaces {
+		iface.WoWLAN = wowlanSupport(iface.Name)
+		if iface.WoWLAN != WoWLANUnsupported {
+			capable = append(capable, iface)
+		}
+	}
+	return capable, nil
+}
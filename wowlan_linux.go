@@ -0,0 +1,32 @@
+package goWake
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// wowlanSupport looks up the wireless PHY of the interface in sysfs and checks
+// whether `iw phy` lists WoWLAN support for it.
+func wowlanSupport(name string) WoWLAN {
+	phy, err := os.ReadFile(filepath.Join("/sys/class/net", name, "phy80211", "name"))
+	if err != nil {
+		if _, err := os.Stat(filepath.Join("/sys/class/net", name)); err != nil {
+			return WoWLANUnknown
+		}
+		if _, err := os.Stat(filepath.Join("/sys/class/net", name, "wireless")); err == nil {
+			return WoWLANUnknown
+		}
+		return WoWLANWired
+	}
+
+	out, err := exec.Command("iw", "phy", strings.TrimSpace(string(phy)), "info").Output()
+	if err != nil {
+		return WoWLANUnknown
+	}
+	if strings.Contains(string(out), "WoWLAN support:") {
+		return WoWLANSupported
+	}
+	return WoWLANUnsupported
+}
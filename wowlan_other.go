@@ -0,0 +1,8 @@
+//go:build !linux
+
+package goWake
+
+// wowlanSupport cannot be determined on this platform.
+func wowlanSupport(name string) WoWLAN {
+	return WoWLANUnknown
+}